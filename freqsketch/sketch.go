@@ -0,0 +1,79 @@
+// Package freqsketch provides a count-min sketch estimating access frequencies of keys
+// in constant memory, e.g. for cache admission decisions.
+package freqsketch
+
+import (
+	"errors"
+	"hash/maphash"
+	"iter"
+	"math"
+	"math/bits"
+)
+
+var ErrInvalidSize = errors.New("invalid sketch size")
+
+// Sketch is a count-min sketch. Estimates never underestimate the number of additions of a key,
+// they overestimate it by at most 2*additions/width with probability at least 1 - 1/2^depth.
+// Sketch is not safe for concurrent use.
+// O(width * depth) memory
+type Sketch[K comparable] struct {
+	seed     maphash.Seed
+	mask     uint64
+	depth    int
+	counters []uint32
+}
+
+// New creates an empty sketch with depth rows of width counters, width is rounded up to a power of two.
+// Returns ErrInvalidSize if width or depth is not positive
+func New[K comparable](width, depth int) (*Sketch[K], error) {
+	if width <= 0 || depth <= 0 {
+		return nil, ErrInvalidSize
+	}
+	width = 1 << bits.Len(uint(width-1))
+	return &Sketch[K]{
+		seed:     maphash.MakeSeed(),
+		mask:     uint64(width - 1),
+		depth:    depth,
+		counters: make([]uint32, width*depth),
+	}, nil
+}
+
+// Add records an occurrence of key, counters saturate instead of overflowing
+// O(depth)
+func (s *Sketch[K]) Add(key K) {
+	for i := range s.indexes(key) {
+		if s.counters[i] < math.MaxUint32 {
+			s.counters[i]++
+		}
+	}
+}
+
+// Estimate returns the estimated number of occurrences of key
+// O(depth)
+func (s *Sketch[K]) Estimate(key K) uint32 {
+	estimate := uint32(math.MaxUint32)
+	for i := range s.indexes(key) {
+		estimate = min(estimate, s.counters[i])
+	}
+	return estimate
+}
+
+// Reset forgets all occurrences
+// O(width * depth)
+func (s *Sketch[K]) Reset() {
+	clear(s.counters)
+}
+
+// indexes yields the counter of key in every row using double hashing
+func (s *Sketch[K]) indexes(key K) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		h := maphash.Comparable(s.seed, key)
+		step := h>>32 | 1
+		width := int(s.mask + 1)
+		for row := range s.depth {
+			if !yield(row*width + int((h+uint64(row)*step)&s.mask)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package freqsketch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	t.Parallel()
+
+	_, err := New[int](0, 4)
+	require.ErrorIs(t, err, ErrInvalidSize)
+
+	sketch, err := New[string](1000, 4)
+	require.NoError(t, err)
+	require.Len(t, sketch.counters, 1024*4)
+
+	for range 50 {
+		sketch.Add("hot")
+	}
+	sketch.Add("cold")
+
+	require.GreaterOrEqual(t, sketch.Estimate("hot"), uint32(50))
+	require.Less(t, sketch.Estimate("hot"), uint32(55))
+	require.GreaterOrEqual(t, sketch.Estimate("cold"), uint32(1))
+	require.Less(t, sketch.Estimate("cold"), uint32(5))
+	require.Less(t, sketch.Estimate("missing"), uint32(5))
+
+	sketch.Reset()
+	require.Zero(t, sketch.Estimate("hot"))
+}
+
+func TestNeverUnderestimates(t *testing.T) {
+	t.Parallel()
+
+	sketch, err := New[int](64, 3)
+	require.NoError(t, err)
+
+	for key := range 1000 {
+		for range key % 7 {
+			sketch.Add(key)
+		}
+	}
+	for key := range 1000 {
+		require.GreaterOrEqual(t, sketch.Estimate(key), uint32(key%7))
+	}
+}
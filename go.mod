@@ -1,6 +1,6 @@
 module lfucache
 
-go 1.23.1
+go 1.24.0
 
 require github.com/stretchr/testify v1.9.0
 
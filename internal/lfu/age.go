@@ -0,0 +1,46 @@
+package lfu
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNoTimestamps = errors.New("timestamps are not recorded")
+
+// WithTimestamps makes the cache record the time of insertion and of the last access of every entry,
+// which are reported by Age and IdleTime. It adds a clock reading to every Get and Put.
+func WithTimestamps[K comparable, V any]() Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		c.extend().timestamps = true
+		return nil
+	}
+}
+
+// Age returns the time since the key was inserted into the cache if the key exists in the cache,
+// otherwise, returns ErrKeyNotFound. Replacing the value does not reset the age.
+// Returns ErrNoTimestamps unless WithTimestamps is set.
+//
+// O(1)
+func (l *cacheImpl[K, V]) Age(key K) (time.Duration, error) {
+	if l.ext == nil || !l.ext.timestamps {
+		return 0, ErrNoTimestamps
+	}
+	if _, ok := l.keyToElement[key]; ok {
+		return time.Duration(l.clock() - l.ext.states[key].inserted), nil
+	}
+	return 0, ErrKeyNotFound
+}
+
+// IdleTime returns the time since the last Get or Put of the key if the key exists in the cache,
+// otherwise, returns ErrKeyNotFound. Returns ErrNoTimestamps unless WithTimestamps is set.
+//
+// O(1)
+func (l *cacheImpl[K, V]) IdleTime(key K) (time.Duration, error) {
+	if l.ext == nil || !l.ext.timestamps {
+		return 0, ErrNoTimestamps
+	}
+	if _, ok := l.keyToElement[key]; ok {
+		return time.Duration(l.clock() - l.ext.states[key].accessed), nil
+	}
+	return 0, ErrKeyNotFound
+}
@@ -0,0 +1,71 @@
+package lfu
+
+import "errors"
+
+var ErrInvalidOp = errors.New("invalid operation")
+
+// OpKind is the kind of operation applied by Apply
+type OpKind int
+
+const (
+	// OpPut puts Value by Key
+	OpPut OpKind = iota
+	// OpRemove removes Key
+	OpRemove
+	// OpClear removes all entries, Key and Value are ignored
+	OpClear
+)
+
+// Op is a single operation of a batch applied by Apply
+type Op[K comparable, V any] struct {
+	Kind  OpKind
+	Key   K
+	Value V
+}
+
+func (l *cacheImpl[K, V]) Apply(ops []Op[K, V]) error {
+	var weights []int64
+	if l.ext != nil && l.ext.weigher != nil {
+		weights = make([]int64, len(ops))
+	}
+	for i, op := range ops {
+		switch op.Kind {
+		case OpPut:
+			if weights == nil {
+				continue
+			}
+			var err error
+			if weights[i], err = l.weigh(op.Key, op.Value); err != nil {
+				return err
+			}
+			if l.tooHeavy(op.Key, weights[i]) {
+				return ErrValueTooHeavy
+			}
+		case OpRemove, OpClear:
+		default:
+			return ErrInvalidOp
+		}
+	}
+
+	l.begin()
+	for i, op := range ops {
+		switch op.Kind {
+		case OpRemove:
+			_ = l.Remove(op.Key)
+			continue
+		case OpClear:
+			l.Clear()
+			continue
+		}
+		if l.ext != nil && l.ext.hooked {
+			l.beforeOp(RecordPut, op.Key)
+		}
+		var weight int64
+		if weights != nil {
+			weight = weights[i]
+		}
+		l.putWeighed(op.Key, op.Value, weight)
+	}
+	l.end()
+	return nil
+}
@@ -0,0 +1,111 @@
+package lfu
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("loader circuit is open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a circuit breaker of the loader. It opens when the rate of failed calls among
+// the last window calls exceeds maxErrorRate, rejects calls for cooldown and then lets
+// a single probe call through: the circuit closes if the probe succeeds and opens again otherwise
+type breaker struct {
+	outcomes     []bool
+	next         int
+	calls        int
+	failures     int
+	maxErrorRate float64
+	cooldown     int64
+	state        breakerState
+	openedAt     int64
+	probing      bool
+}
+
+// WithCircuitBreaker protects the backend of the loader: when more than maxErrorRate of the last
+// window loader calls fail, LoadMulti returns the cached values with ErrCircuitOpen and Prefetch
+// is dropped without calling the loader for cooldown. After that a single call probes the loader,
+// the circuit closes if it succeeds and stays open for another cooldown otherwise.
+// Errors caused by the cancellation of the context are not counted as failures.
+// Returns ErrInvalidOption if window is not positive, maxErrorRate is not in [0, 1) or cooldown is not positive.
+func WithCircuitBreaker[K comparable, V any](window int, maxErrorRate float64, cooldown time.Duration) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if window < 1 || maxErrorRate < 0 || maxErrorRate >= 1 || cooldown <= 0 {
+			return ErrInvalidOption
+		}
+		c.extend().breaker = &breaker{
+			outcomes:     make([]bool, window),
+			maxErrorRate: maxErrorRate,
+			cooldown:     cooldown.Nanoseconds(),
+		}
+		return nil
+	}
+}
+
+func (b *breaker) allow(now int64) bool {
+	switch b.state {
+	case breakerOpen:
+		if now-b.openedAt < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = false
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+func (b *breaker) record(err error, now int64) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		b.probing = false
+		return
+	}
+	failed := err != nil
+	switch b.state {
+	case breakerHalfOpen:
+		if failed {
+			b.open(now)
+		} else {
+			b.state = breakerClosed
+			b.probing = false
+		}
+	case breakerClosed:
+		if b.calls == len(b.outcomes) {
+			if b.outcomes[b.next] {
+				b.failures--
+			}
+		} else {
+			b.calls++
+		}
+		b.outcomes[b.next] = failed
+		b.next = (b.next + 1) % len(b.outcomes)
+		if failed {
+			b.failures++
+		}
+		if b.calls == len(b.outcomes) && float64(b.failures) > b.maxErrorRate*float64(b.calls) {
+			b.open(now)
+		}
+	}
+}
+
+func (b *breaker) open(now int64) {
+	b.state = breakerOpen
+	b.openedAt = now
+	b.probing = false
+	clear(b.outcomes)
+	b.next, b.calls, b.failures = 0, 0, 0
+}
@@ -0,0 +1,135 @@
+package lfu
+
+// Reason describes why an entry left the cache
+type Reason int
+
+const (
+	// ReasonEvicted - the entry was evicted to free space or under memory pressure
+	ReasonEvicted Reason = iota
+	// ReasonRemoved - the entry was removed by Remove
+	ReasonRemoved
+	// ReasonReplaced - the value of the entry was replaced by Put
+	ReasonReplaced
+	// ReasonCleared - the entry was removed by Clear
+	ReasonCleared
+	// ReasonExpired - the entry outlived the TTL set by WithTTL
+	ReasonExpired
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonRemoved:
+		return "removed"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonCleared:
+		return "cleared"
+	case ReasonExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// WithOnEvict sets the callback invoked with the entry and the reason
+// every time an entry leaves the cache. Returns ErrInvalidOption if onEvict is nil.
+func WithOnEvict[K comparable, V any](onEvict func(Entry[K, V], Reason)) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if onEvict == nil {
+			return ErrInvalidOption
+		}
+		c.extend().onEvict = onEvict
+		return nil
+	}
+}
+
+// PutWithCallback works like Put and sets onEvict, which is invoked exactly once when the entry
+// leaves the cache or its value is replaced, in addition to the callback set by WithOnEvict.
+// Callbacks are invoked after the cache is updated and must not modify the cache.
+//
+// O(1)
+func (l *cacheImpl[K, V]) PutWithCallback(key K, value V, onEvict func(Entry[K, V], Reason)) {
+	l.put(key, value)
+	if onEvict == nil {
+		return
+	}
+	if _, ok := l.keyToElement[key]; !ok {
+		l.safely("eviction callback", func() { onEvict(Entry[K, V]{Key: key, Value: value}, ReasonEvicted) })
+		return
+	}
+	x := l.extend()
+	if x.callbacks == nil {
+		x.callbacks = make(map[K]func(Entry[K, V], Reason))
+	}
+	x.callbacks[key] = onEvict
+}
+
+// hasCallbacks reports whether leaving entries have to be reported
+func (l *cacheImpl[K, V]) hasCallbacks() bool {
+	return l.ext != nil && (l.ext.onEvict != nil || len(l.ext.callbacks) > 0)
+}
+
+// event is a notification postponed until the operations in progress finish:
+// the entry leaving the cache with its per-entry callback, or the operation streamed to followers
+type event[K comparable, V any] struct {
+	entry      Entry[K, V]
+	reason     Reason
+	callback   func(Entry[K, V], Reason)
+	op         Op[K, V]
+	replicated bool
+}
+
+// begin postpones notifications until the matching end.
+// A cache without extensions has nothing to notify, so nothing is postponed
+func (l *cacheImpl[K, V]) begin() {
+	if l.ext != nil {
+		l.ext.deferDepth++
+	}
+}
+
+// end delivers the postponed notifications and the evicted batch if the outermost operation has finished
+func (l *cacheImpl[K, V]) end() {
+	x := l.ext
+	if x == nil {
+		return
+	}
+	if x.deferDepth--; x.deferDepth > 0 {
+		return
+	}
+	deferred := x.deferred
+	x.deferred = nil
+	for _, e := range deferred {
+		if e.replicated {
+			l.replicateOp(e.op)
+		} else {
+			l.invoke(e.callback, e.entry, e.reason)
+		}
+	}
+	l.flushEvicted()
+}
+
+// notify invokes the per-entry callback of the entry, if any, and the global one,
+// or postpones them until the operations in progress finish
+func (l *cacheImpl[K, V]) notify(entry Entry[K, V], reason Reason) {
+	callback := l.ext.callbacks[entry.Key]
+	if callback != nil {
+		delete(l.ext.callbacks, entry.Key)
+	}
+	if l.ext.deferDepth > 0 {
+		l.ext.deferred = append(l.ext.deferred, event[K, V]{entry: entry, reason: reason, callback: callback})
+		return
+	}
+	l.invoke(callback, entry, reason)
+}
+
+// invoke invokes the per-entry callback, if any, and the global one
+func (l *cacheImpl[K, V]) invoke(callback func(Entry[K, V], Reason), entry Entry[K, V], reason Reason) {
+	if callback != nil {
+		l.safely("eviction callback", func() { callback(entry, reason) })
+	}
+	if l.ext.onEvict != nil {
+		l.safely("eviction callback", func() { l.ext.onEvict(entry, reason) })
+	}
+}
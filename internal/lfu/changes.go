@@ -0,0 +1,80 @@
+package lfu
+
+import (
+	"errors"
+	"lfucache/internal/linkedlist"
+	"slices"
+)
+
+var ErrHistoryTruncated = errors.New("change history is truncated")
+
+// Change describes the last change of a key
+type Change[K comparable] struct {
+	Key K
+	// Removed is true if the key left the cache, otherwise it was inserted or updated
+	Removed bool
+	// Generation is the generation of the cache right after the change
+	Generation uint64
+}
+
+// WithChangeHistory makes the cache remember the last n changes for ChangesSince.
+// Returns ErrInvalidOption if n is not positive.
+func WithChangeHistory[K comparable, V any](n int) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if n <= 0 {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		x.history = linkedlist.NewBounded[Change[K]](n)
+		x.historyStart = c.generation
+		return nil
+	}
+}
+
+// changed bumps the generation and records the change of the key
+func (l *cacheImpl[K, V]) changed(key K, removed bool) {
+	l.generation++
+	if l.ext == nil || l.ext.history == nil {
+		return
+	}
+	change := Change[K]{Key: key, Removed: removed, Generation: l.generation}
+	if _, dropped, ok := l.ext.history.PushFrontEvict(change); ok {
+		l.ext.historyStart = dropped.Generation
+	}
+}
+
+// cleared bumps the generation and forgets the history, as it can not describe the removal of all keys
+func (l *cacheImpl[K, V]) cleared() {
+	l.generation++
+	if l.ext != nil && l.ext.history != nil {
+		l.ext.history.Clear()
+		l.ext.historyStart = l.generation
+	}
+}
+
+func (l *cacheImpl[K, V]) Generation() uint64 {
+	return l.generation
+}
+
+func (l *cacheImpl[K, V]) ChangesSince(gen uint64) ([]Change[K], error) {
+	if gen == l.generation {
+		return nil, nil
+	}
+	// a generation ahead of the cache comes from another instance, e.g. one before a restart
+	if gen > l.generation || l.ext == nil || l.ext.history == nil || gen < l.ext.historyStart {
+		return nil, ErrHistoryTruncated
+	}
+	seen := make(map[K]struct{})
+	var changes []Change[K]
+	for change := range l.ext.history.All() {
+		if change.Generation <= gen {
+			break
+		}
+		if _, ok := seen[change.Key]; !ok {
+			seen[change.Key] = struct{}{}
+			changes = append(changes, change)
+		}
+	}
+	slices.Reverse(changes)
+	return changes, nil
+}
@@ -0,0 +1,107 @@
+package lfu
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// coalescingLoader is a BulkLoader safe for concurrent use, which merges the keys requested
+// by concurrent calls within a window into one call of the backend loader.
+// The structure of coalescing loader is:
+// 1. loader - the backend loader
+// 2. window - time the keys of a batch are collected for
+// 3. inflight - batch of every key being collected or loaded, callers requesting the key wait for it
+// 4. collecting - batch collecting keys until its window passes, nil if there is none
+type coalescingLoader[K comparable, V any] struct {
+	loader BulkLoader[K, V]
+	window time.Duration
+
+	mu         sync.Mutex
+	inflight   map[K]*loadBatch[K, V]
+	collecting *loadBatch[K, V]
+}
+
+// loadBatch is a single call of the backend loader, done is closed when its result is known
+type loadBatch[K comparable, V any] struct {
+	ctx    context.Context
+	keys   []K
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+// NewCoalescingLoader wraps loader, so concurrent misses are loaded with a single backend call:
+// the keys requested within window after the first missing one are loaded together, and a key
+// already being loaded is not requested again, the callers wait for the pending call instead.
+// The backend is called with the context of the first caller of the batch stripped of its cancellation,
+// a caller whose ctx is done stops waiting and gets its error. Loader panics are returned as errors
+// wrapping ErrCallbackPanic. Returns ErrInvalidOption if loader is nil or window is negative.
+func NewCoalescingLoader[K comparable, V any](loader BulkLoader[K, V], window time.Duration) (*coalescingLoader[K, V], error) {
+	if loader == nil || window < 0 {
+		return nil, ErrInvalidOption
+	}
+	return &coalescingLoader[K, V]{loader: loader, window: window, inflight: make(map[K]*loadBatch[K, V])}, nil
+}
+
+// LoadMany loads the keys together with the keys of concurrent calls
+func (c *coalescingLoader[K, V]) LoadMany(ctx context.Context, keys []K) (map[K]V, error) {
+	batches := make(map[K]*loadBatch[K, V], len(keys))
+	c.mu.Lock()
+	for _, key := range keys {
+		batch, ok := c.inflight[key]
+		if !ok {
+			batch = c.collect(ctx)
+			batch.keys = append(batch.keys, key)
+			c.inflight[key] = batch
+		}
+		batches[key] = batch
+	}
+	c.mu.Unlock()
+
+	values := make(map[K]V, len(keys))
+	for key, batch := range batches {
+		select {
+		case <-batch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		if value, ok := batch.values[key]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// collect returns the batch collecting keys, starting a new one if there is none
+func (c *coalescingLoader[K, V]) collect(ctx context.Context) *loadBatch[K, V] {
+	if c.collecting == nil {
+		batch := &loadBatch[K, V]{ctx: context.WithoutCancel(ctx), done: make(chan struct{})}
+		c.collecting = batch
+		time.AfterFunc(c.window, func() { c.run(batch) })
+	}
+	return c.collecting
+}
+
+// run closes the batch for new keys, loads them and wakes up the waiting callers
+func (c *coalescingLoader[K, V]) run(batch *loadBatch[K, V]) {
+	c.mu.Lock()
+	if c.collecting == batch {
+		c.collecting = nil
+	}
+	c.mu.Unlock()
+
+	batch.values, batch.err = load(batch.ctx, c.loader, batch.keys)
+
+	c.mu.Lock()
+	for _, key := range batch.keys {
+		if c.inflight[key] == batch {
+			delete(c.inflight, key)
+		}
+	}
+	c.mu.Unlock()
+	close(batch.done)
+}
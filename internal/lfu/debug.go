@@ -0,0 +1,95 @@
+package lfu
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithRedactor sets the function formatting values for debug output, e.g. DebugDump,
+// so secrets in cached values never leak into logs or admin endpoints.
+// Without it values are formatted with %v. Returns ErrInvalidOption if redact is nil.
+func WithRedactor[K comparable, V any](redact func(K, V) string) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if redact == nil {
+			return ErrInvalidOption
+		}
+		c.extend().redact = redact
+		return nil
+	}
+}
+
+// describe formats the value for debug output
+func (l *cacheImpl[K, V]) describe(key K, value V) string {
+	if l.ext != nil && l.ext.redact != nil {
+		described, err := guarded(l.reportPanic, "redactor", func() string { return l.ext.redact(key, value) })
+		if err != nil {
+			return "[redactor panicked]"
+		}
+		return described
+	}
+	return fmt.Sprint(value)
+}
+
+// DebugDump writes a line per entry in the order of All: the key, the frequency and the value
+// formatted by the function set by WithRedactor, separated by tabs. Returns the first write error.
+//
+// O(capacity)
+func (l *cacheImpl[K, V]) DebugDump(w io.Writer) error {
+	for elem := range l.elemList.All() {
+		if _, err := fmt.Fprintf(w, "%v\t%d\t%s\n", elem.key, l.frequency(elem), l.describe(elem.key, elem.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errWriter remembers the first write error and skips the writes after it
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...any) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// ExportDOT writes the internal structure of the cache as a Graphviz DOT graph: the ring of elements
+// from the head, the blocks of elements with the same frequency and the starts of the blocks.
+// Values are formatted by the function set by WithRedactor. Returns the first write error.
+//
+// O(capacity)
+func (l *cacheImpl[K, V]) ExportDOT(w io.Writer) error {
+	out := &errWriter{w: w}
+	out.printf("digraph lfu {\n\trankdir=LR;\n\tnode [shape=box];\n\thead [shape=point];\n")
+	i := 0
+	freq := 0
+	for link := range l.elemList.Nodes() {
+		elem := link.Value
+		if i == 0 || elem.freq != freq {
+			if i > 0 {
+				out.printf("\t}\n")
+			}
+			freq = elem.freq
+			out.printf("\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", freq, fmt.Sprintf("frequency %d, %d elements", freq, l.freqToCount[freq]))
+		}
+		out.printf("\t\te%d [label=%q];\n", i, fmt.Sprintf("%v\n%s", elem.key, l.describe(elem.key, elem.value)))
+		if l.freqToStart[freq] == link {
+			out.printf("\t\tstart%d [shape=plaintext, label=%q];\n\t\tstart%d -> e%d [style=dashed];\n",
+				freq, fmt.Sprintf("start of %d", freq), freq, i)
+		}
+		i++
+	}
+	if i > 0 {
+		out.printf("\t}\n")
+	}
+
+	prev := "head"
+	for j := range i {
+		out.printf("\t%s -> e%d;\n", prev, j)
+		prev = fmt.Sprintf("e%d", j)
+	}
+	out.printf("\t%s -> head;\n}\n", prev)
+	return out.err
+}
@@ -0,0 +1,42 @@
+package lfu
+
+// Entry describes a cache entry
+type Entry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	Frequency int
+	// Meta is the opaque metadata attached by PutWithMeta, it does not affect the eviction policy
+	Meta any
+}
+
+// entry describes the element
+func (l *cacheImpl[K, V]) entry(elem *element[K, V]) Entry[K, V] {
+	entry := Entry[K, V]{Key: elem.key, Value: elem.value, Frequency: l.frequency(elem)}
+	if l.ext != nil {
+		entry.Meta = l.ext.meta[elem.key]
+	}
+	return entry
+}
+
+// PutWithMeta works like Put and attaches opaque metadata to the entry, which is returned
+// by EntryInfo and passed to eviction callbacks. Put keeps the attached metadata.
+//
+// O(1)
+func (l *cacheImpl[K, V]) PutWithMeta(key K, value V, meta any) {
+	l.put(key, value)
+	if _, ok := l.keyToElement[key]; !ok {
+		return
+	}
+	x := l.extend()
+	if x.meta == nil {
+		x.meta = make(map[K]any)
+	}
+	x.meta[key] = meta
+}
+
+func (l *cacheImpl[K, V]) EntryInfo(key K) (Entry[K, V], error) {
+	if link, ok := l.keyToElement[key]; ok {
+		return l.entry(link.Value), nil
+	}
+	return Entry[K, V]{}, ErrKeyNotFound
+}
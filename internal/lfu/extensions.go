@@ -0,0 +1,150 @@
+package lfu
+
+import (
+	"lfucache/internal/linkedlist"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// extensions holds the optional features of the cache, allocated by the first option enabling one of them.
+// The structure of extensions is:
+// 1. pressure - memory pressure watcher, nil unless soft-value mode is enabled
+// 2. tieBreak - random generator choosing victims among the least frequently used elements, nil for LRU
+// 3. loader - loader of the keys missing in the cache, nil if not configured
+// 4. onEvict - callback for every entry leaving the cache, nil if not configured
+// 5. callbacks - per-entry callbacks set by PutWithCallback, nil until the first one is set
+// 6. meta - per-entry metadata set by PutWithMeta, nil until the first one is set
+// 7. incrementInterval - minimal interval between frequency increments of a key in nanoseconds, 0 if unlimited
+// 8. history - the last changes of the cache, nil unless WithChangeHistory is set
+// 9. historyStart - generation since which the history is complete
+// 10. logFrequency - frequencies are bucketed logarithmically, so blocks are powers of two
+// 11. morris - random generator for probabilistic increments, nil if frequencies are exact
+// 12. morrisFactor - the higher it is, the less likely increments of high frequencies are
+// 13. prefetchSlots - semaphore bounding the number of running prefetches, nil until the first prefetch
+// 14. prefetched - values loaded by finished prefetches, put into the cache by the next Get or Put
+// 15. breaker - circuit breaker of the loader, nil if not configured
+// 16. namespaces - namespaces of the keys and their quotas, nil if not configured
+// 17. quotaBorrowing - namespaces can borrow the unused quota of the others
+// 18. softLimit - size above which entries are evicted off the request path, 0 if not configured
+// 19. weigher - function returning the weight of an entry, nil if the weight is not limited
+// 20. maxWeight - maximum total weight of the entries
+// 21. onEvictBatch - callback for all entries evicted to make room for an entry, nil if not configured
+// 22. evicted - entries evicted to make room for the entry being put, collected for onEvictBatch
+// 23. sampleRate - number of accesses per move of an element between blocks, 0 if every access moves it
+// 24. timestamps - times of insertion and of the last access of elements are recorded
+// 25. recorder - writer of the trace of operations, nil if not configured
+// 26. redact - function formatting values for debug output, nil to format them with %v
+// 27. reporter - periodic reporter of the statistics, nil if not configured
+// 28. indexes - secondary indexes by their names, nil if not configured
+// 29. interned - canonical copies of the values shared by entries, nil unless values are interned
+// 30. maxValueWeight - maximum weight of a single value, 0 if only the total weight is limited
+// 31. replicate - sink of the changes streamed to followers, nil if not configured
+// 32. panicHandler - handler of the panics recovered from the callbacks, nil if not configured
+// 33. hooked - whether Get and Put run beforeOp, set by the options adding a recorder, a memory pressure
+// watcher, a stats reporter, prefetching or a TTL
+// 34. deferDepth - number of operations in progress postponing notifications until the outermost one finishes
+// 35. deferred - notifications postponed until the operations in progress finish
+// 36. namespaceMaxWeights - max weights of namespaces set by WithNamespaceMaxWeights, passed to namespaces
+// 37. trimInterval - interval of the background trimming, 0 if not configured
+// 38. trimLocker - lock guarding the cache held by the background trimming
+// 39. trimDone - closed by Close to stop the background trimming, nil unless it is running
+// 40. states - per-entry data of the features needing it, nil unless one of them is enabled,
+// so elements of a plain cache hold only the key, the value and the frequency
+// 41. ttl - lifetime of the entries in nanoseconds, 0 if they do not expire
+type extensions[K comparable, V any] struct {
+	pressure  *pressureWatcher
+	tieBreak  *rand.Rand
+	loader    BulkLoader[K, V]
+	onEvict   func(Entry[K, V], Reason)
+	callbacks map[K]func(Entry[K, V], Reason)
+	meta      map[K]any
+
+	incrementInterval int64
+
+	history      linkedlist.List[Change[K]]
+	historyStart uint64
+	logFrequency bool
+	morris       *rand.Rand
+	morrisFactor int
+
+	prefetchSlots chan struct{}
+	prefetched    chan prefetchResult[K, V]
+	breaker       *breaker
+	namespaces    *namespaces[K]
+
+	quotaBorrowing bool
+	softLimit      int
+
+	weigher      func(K, V) int64
+	maxWeight    int64
+	onEvictBatch func([]Entry[K, V])
+	evicted      []Entry[K, V]
+	sampleRate   int
+	timestamps   bool
+	recorder     *recorder[K]
+	redact       func(K, V) string
+	reporter     *statsReporter
+	indexes      map[string]valueIndex[K, V]
+	interned     *internPool[V]
+
+	maxValueWeight int64
+	replicate      func(Op[K, V])
+	panicHandler   func(error)
+	hooked         bool
+
+	deferDepth int
+	deferred   []event[K, V]
+
+	namespaceMaxWeights map[string]int64
+	trimInterval        time.Duration
+	trimLocker          sync.Locker
+	trimDone            chan struct{}
+
+	states map[K]*entryState
+	ttl    int64
+}
+
+// entryState holds the data of an entry used by the optional features.
+// The structure of entryState is:
+// 1. bumped - time of the last frequency increment in nanoseconds, set only if increments are rate-limited
+// 2. hits - exact number of accesses, counted only if frequencies are bucketed logarithmically
+// 3. weight - weight of the entry, set only if the weight of the cache is limited
+// 4. pending - number of accesses since the last move of the element, counted only if accesses are sampled
+// 5. inserted - time of the insertion in nanoseconds, set only if timestamps are recorded
+// 6. accessed - time of the last access in nanoseconds, set only if timestamps are recorded
+// 7. expires - time of the expiration in nanoseconds, set only if entries have a TTL
+type entryState struct {
+	bumped   int64
+	hits     int
+	weight   int64
+	pending  int
+	inserted int64
+	accessed int64
+	expires  int64
+}
+
+// extend returns the optional features of the cache, allocating them if none is enabled yet
+func (l *cacheImpl[K, V]) extend() *extensions[K, V] {
+	if l.ext == nil {
+		l.ext = &extensions[K, V]{}
+	}
+	return l.ext
+}
+
+// addState records the data of a new entry used by the optional features
+func (l *cacheImpl[K, V]) addState(key K, hits int, weight int64) {
+	x := l.ext
+	state := &entryState{hits: hits, weight: weight}
+	if x.incrementInterval > 0 {
+		state.bumped = l.clock()
+	}
+	if x.timestamps {
+		state.inserted = l.clock()
+		state.accessed = state.inserted
+	}
+	if x.ttl > 0 {
+		state.expires = l.clock() + x.ttl
+	}
+	x.states[key] = state
+}
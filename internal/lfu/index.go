@@ -0,0 +1,116 @@
+package lfu
+
+import "iter"
+
+// valueIndex maps attributes of values to the keys of the entries having them
+type valueIndex[K comparable, V any] interface {
+	add(key K, value V)
+	remove(key K)
+	lookup(attr any) iter.Seq[K]
+	clear()
+}
+
+// attrIndex remembers the attribute of every indexed key, so removing a key does not call attr
+type attrIndex[K comparable, V any, I comparable] struct {
+	attr   func(V) I
+	report func(error)
+	keys   map[I]map[K]struct{}
+	attrs  map[K]I
+}
+
+// WithIndex adds the secondary index named name, which maps the attribute returned by attr
+// for the value of every entry to the keys of the entries, so LookupIndex finds entries by attribute
+// without a full scan. The index is updated by every insertion, replacement and removal of an entry.
+// An index with the same name replaces the previous one. Returns ErrInvalidOption if attr is nil.
+func WithIndex[K comparable, V any, I comparable](name string, attr func(V) I) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if attr == nil {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		if x.indexes == nil {
+			x.indexes = make(map[string]valueIndex[K, V])
+		}
+		x.indexes[name] = &attrIndex[K, V, I]{
+			attr:   attr,
+			report: c.reportPanic,
+			keys:   make(map[I]map[K]struct{}),
+			attrs:  make(map[K]I),
+		}
+		return nil
+	}
+}
+
+// add indexes the key by the attribute of the value, the key is not indexed if attr panics
+func (x *attrIndex[K, V, I]) add(key K, value V) {
+	attr, err := guarded(x.report, "index attribute", func() I { return x.attr(value) })
+	if err != nil {
+		return
+	}
+	x.attrs[key] = attr
+	keys, ok := x.keys[attr]
+	if !ok {
+		keys = make(map[K]struct{})
+		x.keys[attr] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func (x *attrIndex[K, V, I]) remove(key K) {
+	attr, ok := x.attrs[key]
+	if !ok {
+		return
+	}
+	delete(x.attrs, key)
+	if keys, ok := x.keys[attr]; ok {
+		if delete(keys, key); len(keys) == 0 {
+			delete(x.keys, attr)
+		}
+	}
+}
+
+func (x *attrIndex[K, V, I]) lookup(attr any) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		i, ok := attr.(I)
+		if !ok {
+			return
+		}
+		for key := range x.keys[i] {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+func (x *attrIndex[K, V, I]) clear() {
+	clear(x.keys)
+	clear(x.attrs)
+}
+
+// indexed updates the secondary indexes for the value of the key being added or removed
+func (l *cacheImpl[K, V]) indexed(key K, value V, added bool) {
+	for _, index := range l.ext.indexes {
+		if added {
+			index.add(key, value)
+		} else {
+			index.remove(key)
+		}
+	}
+}
+
+// LookupIndex returns the iterator over the keys of the entries whose values have the attribute attr
+// in the secondary index named name set by WithIndex. It yields nothing if there is no such index
+// or attr is not of the type of its attributes.
+//
+// O(number of the keys)
+func (l *cacheImpl[K, V]) LookupIndex(name string, attr any) iter.Seq[K] {
+	if l.ext == nil {
+		return func(func(K) bool) {}
+	}
+	index, ok := l.ext.indexes[name]
+	if !ok {
+		return func(func(K) bool) {}
+	}
+	return index.lookup(attr)
+}
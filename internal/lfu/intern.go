@@ -0,0 +1,104 @@
+package lfu
+
+import (
+	"hash/maphash"
+	"slices"
+)
+
+// internPool keeps a single canonical copy of equal values with the number of entries sharing it
+type internPool[V any] struct {
+	hash   func(V) uint64
+	equal  func(V, V) bool
+	report func(error)
+	values map[uint64][]*internedValue[V]
+	size   int
+}
+
+type internedValue[V any] struct {
+	value V
+	refs  int
+}
+
+// WithInterning makes entries with equal values share one copy of the value, e.g. the backing array
+// of a string or a slice: a value put into the cache is replaced by the equal value already stored,
+// which is released when the last entry holding it leaves the cache. hash must return equal hashes
+// for equal values. Returns ErrInvalidOption if hash or equal is nil.
+func WithInterning[K comparable, V any](hash func(V) uint64, equal func(V, V) bool) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if hash == nil || equal == nil {
+			return ErrInvalidOption
+		}
+		c.extend().interned = &internPool[V]{hash: hash, equal: equal, report: c.reportPanic, values: make(map[uint64][]*internedValue[V])}
+		return nil
+	}
+}
+
+// WithComparableInterning works like WithInterning for comparable values, e.g. strings
+func WithComparableInterning[K, V comparable]() Option[K, V] {
+	seed := maphash.MakeSeed()
+	return WithInterning[K](func(value V) uint64 {
+		return maphash.Comparable(seed, value)
+	}, func(a, b V) bool {
+		return a == b
+	})
+}
+
+// intern returns the canonical copy of the value and counts one more reference to it.
+// The value itself is returned and not counted if hash or equal panics
+func (p *internPool[V]) intern(value V) V {
+	hash, interned, err := p.find(value)
+	if err != nil {
+		return value
+	}
+	if interned != nil {
+		interned.refs++
+		return interned.value
+	}
+	p.values[hash] = append(p.values[hash], &internedValue[V]{value: value, refs: 1})
+	p.size++
+	return value
+}
+
+// find returns the hash of the value and its canonical copy, or nil if there is none,
+// or an error if hash or equal panics
+func (p *internPool[V]) find(value V) (uint64, *internedValue[V], error) {
+	hash, err := guarded(p.report, "interning hash", func() uint64 { return p.hash(value) })
+	if err != nil {
+		return 0, nil, err
+	}
+	found, err := guarded(p.report, "interning equal", func() *internedValue[V] {
+		for _, interned := range p.values[hash] {
+			if p.equal(interned.value, value) {
+				return interned
+			}
+		}
+		return nil
+	})
+	return hash, found, err
+}
+
+// release counts one reference less to the canonical copy of the value and forgets it if it is unused
+func (p *internPool[V]) release(value V) {
+	hash, found, err := p.find(value)
+	if err != nil || found == nil {
+		return
+	}
+	if found.refs--; found.refs > 0 {
+		return
+	}
+	p.size--
+	bucket := p.values[hash]
+	if len(bucket) == 1 {
+		delete(p.values, hash)
+		return
+	}
+	i := slices.Index(bucket, found)
+	bucket[i] = bucket[len(bucket)-1]
+	bucket[len(bucket)-1] = nil
+	p.values[hash] = bucket[:len(bucket)-1]
+}
+
+func (p *internPool[V]) clear() {
+	clear(p.values)
+	p.size = 0
+}
@@ -0,0 +1,62 @@
+package lfu
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvariantViolated = errors.New("cache invariant violated")
+
+// CheckInvariants verifies the internal block structure of the cache and returns
+// an error wrapping ErrInvariantViolated describing the first violation found.
+// It is intended for tests and debugging.
+//
+// O(capacity)
+func (l *cacheImpl[K, V]) CheckInvariants() error {
+	size := l.elemList.Size()
+	if size != len(l.keyToElement) {
+		return fmt.Errorf("%w: list size %d, key map size %d", ErrInvariantViolated, size, len(l.keyToElement))
+	}
+	if l.ext != nil && l.ext.states != nil && len(l.ext.states) != size {
+		return fmt.Errorf("%w: %d entry states, size is %d", ErrInvariantViolated, len(l.ext.states), size)
+	}
+	if size > l.capacity {
+		return fmt.Errorf("%w: size %d exceeds capacity %d", ErrInvariantViolated, size, l.capacity)
+	}
+	if len(l.freqToStart) != len(l.freqToCount) {
+		return fmt.Errorf("%w: %d block starts, %d block counts", ErrInvariantViolated, len(l.freqToStart), len(l.freqToCount))
+	}
+	total := 0
+	for _, count := range l.freqToCount {
+		total += count
+	}
+	if total != size {
+		return fmt.Errorf("%w: blocks contain %d elements, size is %d", ErrInvariantViolated, total, size)
+	}
+
+	counted := make(map[int]int, len(l.freqToCount))
+	prevFreq := -1
+	for node := range l.elemList.Nodes() {
+		elem := node.Value
+		if elem.freq < 0 || elem.freq > l.maxFreq {
+			return fmt.Errorf("%w: key %v has frequency %d", ErrInvariantViolated, elem.key, elem.freq)
+		}
+		if l.keyToElement[elem.key] != node {
+			return fmt.Errorf("%w: key %v is not mapped to its node", ErrInvariantViolated, elem.key)
+		}
+		if prevFreq >= 0 && elem.freq > prevFreq {
+			return fmt.Errorf("%w: frequency %d follows %d", ErrInvariantViolated, elem.freq, prevFreq)
+		}
+		if elem.freq != prevFreq && l.freqToStart[elem.freq] != node {
+			return fmt.Errorf("%w: block %d does not start at key %v", ErrInvariantViolated, elem.freq, elem.key)
+		}
+		counted[elem.freq]++
+		prevFreq = elem.freq
+	}
+	for freq, count := range l.freqToCount {
+		if counted[freq] != count {
+			return fmt.Errorf("%w: block %d contains %d elements, %d expected", ErrInvariantViolated, freq, counted[freq], count)
+		}
+	}
+	return nil
+}
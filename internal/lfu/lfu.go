@@ -1,16 +1,31 @@
+// Package lfu implements a least frequently used cache.
+//
+// Functions and methods of the package never panic on invalid arguments, they return errors instead.
+// The only exception is New, which panics on negative capacity,
+// use NewWithOptions to get ErrInvalidCapacity instead.
 package lfu
 
 import (
 	"errors"
 	"iter"
 	"lfucache/internal/linkedlist"
+	"math"
+	"time"
 )
 
-var ErrKeyNotFound = errors.New("key not found")
+var (
+	ErrKeyNotFound     = errors.New("key not found")
+	ErrInvalidCapacity = errors.New("invalid capacity")
+)
 
 const DefaultCapacity = 5
 
-// Cache
+// MaxFrequency is the default maximum frequency, frequencies saturate instead of overflowing
+const MaxFrequency = math.MaxInt
+
+// Cache is implemented by every cache of the package: the ones created by New, NewWithOptions and NewSampled,
+// and the wrappers of NewSynchronized and NewShadowed. Features depending on options or on the implementation,
+// like loading, timestamps, weights, indexes and debug output, are methods of the concrete types.
 // O(capacity) memory
 type Cache[K comparable, V any] interface {
 	// Get returns the value of the key if the key exists in the cache,
@@ -28,6 +43,47 @@ type Cache[K comparable, V any] interface {
 	// O(1)
 	Put(key K, value V)
 
+	// PutWithEvicted works like Put and additionally returns the key and the value evicted
+	// to make room for the new key and true, or zero values and false if nothing was evicted.
+	// If several entries were evicted because of their weight, the least frequently used one is returned.
+	//
+	// O(1)
+	PutWithEvicted(key K, value V) (K, V, bool)
+
+	// PutTransient works like Put, but marks the entry as likely used once: it is inserted
+	// with frequency 0, so it is evicted before any other entry until it is accessed.
+	// If the cache is full or its weight limit is reached, only other such entries are evicted to make
+	// room for it, otherwise, the entry is not cached. The previous value of the key is replaced in any case.
+	//
+	// O(1)
+	PutTransient(key K, value V)
+
+	// Remove deletes the key from the cache if the key exists,
+	// otherwise, returns ErrKeyNotFound.
+	//
+	// O(1)
+	Remove(key K) error
+
+	// Apply applies the operations in order as one unit: callbacks, the batch eviction callback
+	// and the replication sink are invoked once the whole batch is applied.
+	// If any operation is invalid, returns ErrInvalidOp, or ErrValueTooHeavy if a value would be
+	// rejected because of its weight, or an error wrapping ErrCallbackPanic if the weigher panics,
+	// and the cache is not modified. Removing a missing key is not an error.
+	//
+	// O(len(ops))
+	Apply(ops []Op[K, V]) error
+
+	// GetMulti works like Get for every key and returns values of the keys found in the cache.
+	//
+	// O(len(keys))
+	GetMulti(keys []K) map[K]V
+
+	// PutMulti works like Put for every entry of the map.
+	// Entries are put in the map iteration order, which matters if they do not fit in the cache.
+	//
+	// O(len(entries))
+	PutMulti(entries map[K]V)
+
 	// All returns the iterator in descending order of frequency.
 	// If two or more keys have the same frequency, the most recently used key will be listed first.
 	//
@@ -44,11 +100,67 @@ type Cache[K comparable, V any] interface {
 	// O(1)
 	Capacity() int
 
+	// Clear removes all entries from the cache, the capacity stays the same.
+	//
+	// O(capacity)
+	Clear()
+
 	// GetKeyFrequency returns the element's frequency if the key exists in the cache,
 	// otherwise, returns ErrKeyNotFound.
 	//
 	// O(1)
 	GetKeyFrequency(key K) (int, error)
+
+	// Stats returns a snapshot of the cache statistics.
+	//
+	// O(1)
+	Stats() Stats
+}
+
+// Replica is a cache which can copy the entries of another cache and follow its changes,
+// and can be followed itself. Caches created by New, NewWithOptions and NewSampled are replicas
+type Replica[K comparable, V any] interface {
+	Cache[K, V]
+
+	// EntryInfo returns the entry of the key without changing its frequency if the key exists in the cache,
+	// otherwise, returns ErrKeyNotFound.
+	//
+	// O(1)
+	EntryInfo(key K) (Entry[K, V], error)
+
+	// Generation returns the generation of the cache, which is increased by every insertion,
+	// update and removal of an entry. Accesses that only change frequencies do not increase it.
+	//
+	// O(1)
+	Generation() uint64
+
+	// ChangesSince returns the last change of every key changed after generation gen,
+	// in the order of changes. Returns ErrHistoryTruncated if the changes are not known anymore,
+	// because they did not fit in the history set by WithChangeHistory or the cache was cleared,
+	// or if gen is ahead of the cache, e.g. because it was returned by another instance before a restart.
+	//
+	// O(history size)
+	ChangesSince(gen uint64) ([]Change[K], error)
+
+	// WarmFrom copies up to limit of the hottest entries of src by GetKeyFrequency together with their
+	// frequencies, e.g. to pass the state to a new cache instance. src may yield its entries in any order.
+	// If limit is not positive or exceeds the capacity, the capacity is used. Existing keys are replaced,
+	// entries that fit only by evicting hotter entries, by number or by weight, are skipped.
+	// Returns the number of copied entries, or ErrNilSource if src is nil.
+	//
+	// O(size of src * log(limit) + limit * number of distinct frequencies)
+	WarmFrom(src WarmSource[K, V], limit int) (int, error)
+
+	// SyncFrom makes the cache a follower of src: it applies the changes of src made after generation since,
+	// copying changed entries together with their frequencies like WarmFrom, and returns the generation
+	// of src to pass to the next call. If src no longer knows the changes, e.g. because its history
+	// set by WithChangeHistory is too short, the cache is cleared and warmed from src instead,
+	// provided src implements WarmSource too, like every Cache does.
+	// Returns the error of src.ChangesSince and since if the changes cannot be read otherwise,
+	// or ErrNilSource if src is nil.
+	//
+	// O(number of changes * number of distinct frequencies)
+	SyncFrom(src SyncSource[K, V], since uint64) (uint64, error)
 }
 
 // cacheImpl represents LFU cache implementation
@@ -62,6 +174,13 @@ type Cache[K comparable, V any] interface {
 // 4. freqToCount - map to get number of elements in block by using frequency of elements there
 // 5. capacity - can be set by user, otherwise it will be DefaultCapacity
 // 6. defaultValue
+// 7. maxFreq - frequency at which counters saturate, MaxFrequency unless set by user
+// 8. stats - counters of the events returned by Stats, nil if disabled
+// 9. clock - current time in nanoseconds since the creation of the cache
+// 10. generation - number of changes of the cache
+// 11. weight - total weight of the entries, 0 unless the weight is limited
+// 12. ext - optional features, nil unless one of them is enabled, so Get and Put of a plain cache
+// check a single pointer instead of every feature
 type cacheImpl[K comparable, V any] struct {
 	elemList     linkedlist.List[*element[K, V]]
 	keyToElement map[K]*linkedlist.Node[*element[K, V]]
@@ -69,6 +188,12 @@ type cacheImpl[K comparable, V any] struct {
 	freqToCount  map[int]int
 	capacity     int
 	defaultValue V
+	maxFreq      int
+	stats        *countingStats
+	clock        func() int64
+	generation   uint64
+	weight       int64
+	ext          *extensions[K, V]
 }
 
 type element[K comparable, V any] struct {
@@ -77,6 +202,8 @@ type element[K comparable, V any] struct {
 	freq  int
 }
 
+// New creates a cache with the given capacity or DefaultCapacity.
+// Panics if capacity is negative
 func New[K comparable, V any](capacity ...int) *cacheImpl[K, V] {
 	cap := DefaultCapacity
 	if len(capacity) > 0 {
@@ -85,28 +212,45 @@ func New[K comparable, V any](capacity ...int) *cacheImpl[K, V] {
 	if cap < 0 {
 		panic("invalid capacity")
 	}
+	return newCache[K, V](cap)
+}
+
+func newCache[K comparable, V any](cap int) *cacheImpl[K, V] {
 	return &cacheImpl[K, V]{
 		elemList:     linkedlist.New[*element[K, V]](),
 		keyToElement: make(map[K]*linkedlist.Node[*element[K, V]], cap),
 		freqToStart:  make(map[int]*linkedlist.Node[*element[K, V]], cap),
 		freqToCount:  make(map[int]int, cap),
 		capacity:     cap,
+		maxFreq:      MaxFrequency,
+		clock:        monotonicClock(),
+		stats:        &countingStats{},
 	}
 }
 
-func (l *cacheImpl[K, V]) moveToFront(link *linkedlist.Node[*element[K, V]]) {
+// monotonicClock returns the clock of a cache: nanoseconds since its creation measured with the monotonic clock,
+// so steps of the wall clock do not affect rate limits, cooldowns and ages
+func monotonicClock() func() int64 {
+	start := time.Now()
+	return func() int64 {
+		return int64(time.Since(start))
+	}
+}
+
+// moveToFront moves the element to the start of the existing block of its frequency
+func (l *cacheImpl[K, V]) moveToFront(link, start *linkedlist.Node[*element[K, V]]) {
 	freq := link.Value.freq
-	start := l.freqToStart[freq]
 	l.elemList.Move(link, start)
 	l.freqToStart[freq] = link
 	l.freqToCount[freq]++
 }
 
-func (l *cacheImpl[K, V]) addNewBlock(link *linkedlist.Node[*element[K, V]]) {
+// addNewBlock creates the block of the element, which has just left the block prevFreq
+func (l *cacheImpl[K, V]) addNewBlock(link *linkedlist.Node[*element[K, V]], prevFreq int) {
 	freq := link.Value.freq
 	l.freqToStart[freq] = link
 	l.freqToCount[freq] = 1
-	if next, ok := l.freqToStart[freq-1]; ok {
+	if next, ok := l.freqToStart[prevFreq]; ok {
 		l.elemList.Move(link, next)
 	}
 }
@@ -116,25 +260,239 @@ func (l *cacheImpl[K, V]) deleteBlock(freq int) {
 	delete(l.freqToStart, freq)
 }
 
-func (l *cacheImpl[K, V]) increaseFreq(link *linkedlist.Node[*element[K, V]]) {
+// leaveBlock takes the element out of the block of its frequency, deleting the block if it becomes empty
+func (l *cacheImpl[K, V]) leaveBlock(link *linkedlist.Node[*element[K, V]]) {
 	freq := link.Value.freq
-	l.freqToCount[freq]--
-
-	if l.freqToCount[freq] == 0 {
+	if count := l.freqToCount[freq] - 1; count == 0 {
 		l.deleteBlock(freq)
-	} else if l.freqToStart[freq] == link {
-		l.freqToStart[freq] = link.Next()
+	} else {
+		l.freqToCount[freq] = count
+		if l.freqToStart[freq] == link {
+			l.freqToStart[freq] = link.Next()
+		}
 	}
-	link.Value.freq++
+}
 
-	if _, ok := l.freqToStart[link.Value.freq]; ok {
-		l.moveToFront(link)
+// touch marks the element as the most recently used one in its block without changing its frequency
+func (l *cacheImpl[K, V]) touch(link *linkedlist.Node[*element[K, V]]) {
+	freq := link.Value.freq
+	if start := l.freqToStart[freq]; start != link {
+		l.elemList.Move(link, start)
+		l.freqToStart[freq] = link
+	}
+}
+
+func (l *cacheImpl[K, V]) increaseFreq(link *linkedlist.Node[*element[K, V]]) {
+	x := l.ext
+	if x != nil {
+		if x.timestamps {
+			x.states[link.Value.key].accessed = l.clock()
+		}
+		if x.sampleRate > 0 {
+			state := x.states[link.Value.key]
+			if state.pending++; state.pending >= x.sampleRate {
+				l.catchUp(link)
+			}
+			return
+		}
+	}
+	freq := link.Value.freq
+	if freq >= l.maxFreq {
+		l.record(statSaturation)
+		l.touch(link)
+		return
+	}
+	next := freq + 1
+	if x != nil {
+		if next = l.nextFreq(x, link, freq); next == 0 {
+			l.touch(link)
+			return
+		}
+	}
+	l.leaveBlock(link)
+	link.Value.freq = next
+
+	if start, ok := l.freqToStart[next]; ok {
+		l.moveToFront(link, start)
 	} else {
-		l.addNewBlock(link)
+		l.addNewBlock(link, freq)
+	}
+}
+
+// nextFreq applies the configured rules of increments to the element of frequency freq below the maximum.
+// Returns the frequency the element moves to, or 0 if it stays in its block
+func (l *cacheImpl[K, V]) nextFreq(x *extensions[K, V], link *linkedlist.Node[*element[K, V]], freq int) int {
+	if x.incrementInterval > 0 {
+		now := l.clock()
+		state := x.states[link.Value.key]
+		if now-state.bumped < x.incrementInterval {
+			l.record(statSuppressedIncrement)
+			return 0
+		}
+		state.bumped = now
+	}
+	if x.morris != nil && freq > 0 && x.morris.IntN((freq-1)*x.morrisFactor+1) != 0 {
+		return 0
+	}
+	if x.logFrequency && freq > 0 {
+		state := x.states[link.Value.key]
+		if state.hits++; state.hits < 2*freq {
+			return 0
+		}
+		return min(2*freq, l.maxFreq)
+	}
+	return freq + 1
+}
+
+// catchUp moves the element to the block of its frequency increased by the pending accesses.
+// The blocks between the old and the new frequency are skipped, so it is O(number of skipped blocks)
+func (l *cacheImpl[K, V]) catchUp(link *linkedlist.Node[*element[K, V]]) {
+	freq := link.Value.freq
+	state := l.ext.states[link.Value.key]
+	pending := state.pending
+	state.pending = 0
+	if freq >= l.maxFreq {
+		l.record(statSaturation)
+		l.touch(link)
+		return
+	}
+	next := freq + min(pending, l.maxFreq-freq)
+
+	// the closest element to the back, which stays before the element
+	at := link.Prev()
+	for at != l.elemList.Head() && at.Value.freq < next {
+		at = l.freqToStart[at.Value.freq].Prev()
+	}
+
+	l.leaveBlock(link)
+	link.Value.freq = next
+
+	if start, ok := l.freqToStart[next]; ok {
+		l.moveToFront(link, start)
+		return
+	}
+	l.freqToStart[next] = link
+	l.freqToCount[next] = 1
+	if at.Next() != link {
+		l.elemList.Move(link, at.Next())
+	}
+}
+
+// frequency returns the frequency of the element including the pending accesses
+func (l *cacheImpl[K, V]) frequency(elem *element[K, V]) int {
+	return elem.freq + min(l.pending(elem), l.maxFreq-elem.freq)
+}
+
+// pending returns the number of accesses of the element since its last move, 0 unless accesses are sampled
+func (l *cacheImpl[K, V]) pending(elem *element[K, V]) int {
+	if l.ext == nil || l.ext.sampleRate == 0 {
+		return 0
+	}
+	return l.ext.states[elem.key].pending
+}
+
+// remove deletes the element from its block and from the cache, hands its node back to the list for reuse
+// and notifies eviction callbacks
+func (l *cacheImpl[K, V]) remove(link *linkedlist.Node[*element[K, V]], reason Reason) {
+	elem := link.Value
+	// user functions of interning and namespaces run before the blocks are changed
+	x := l.ext
+	var weight int64
+	if x != nil {
+		if x.states != nil {
+			weight = x.states[elem.key].weight
+			delete(x.states, elem.key)
+		}
+		if x.interned != nil {
+			x.interned.release(elem.value)
+		}
+		if x.indexes != nil {
+			l.indexed(elem.key, elem.value, false)
+		}
+		if x.namespaces != nil {
+			x.namespaces.add(elem.key, -1, -weight)
+		}
+	}
+
+	l.leaveBlock(link)
+	delete(l.keyToElement, elem.key)
+	l.elemList.Remove(link)
+	l.elemList.Release(link)
+	l.weight -= weight
+	l.changed(elem.key, true)
+
+	if l.hasCallbacks() {
+		l.notify(l.entry(elem), reason)
+	}
+	if x != nil && x.meta != nil {
+		delete(x.meta, elem.key)
+	}
+}
+
+// victim returns the least frequently used element.
+// Ties are broken by recency, or randomly if a random tie-break source is set
+func (l *cacheImpl[K, V]) victim() *linkedlist.Node[*element[K, V]] {
+	victim := l.elemList.Back()
+	x := l.ext
+	if x == nil {
+		return victim
+	}
+	for x.sampleRate > 0 && x.states[victim.Value.key].pending > 0 {
+		l.catchUp(victim)
+		victim = l.elemList.Back()
+	}
+	if x.tieBreak != nil {
+		for range x.tieBreak.IntN(l.freqToCount[victim.Value.freq]) {
+			victim = victim.Prev()
+		}
+	}
+	return victim
+}
+
+// evict removes the least frequently used element from the cache and returns it
+func (l *cacheImpl[K, V]) evict() *element[K, V] {
+	victim := l.victim()
+	elem := victim.Value
+	l.remove(victim, ReasonEvicted)
+	return elem
+}
+
+// shed drops the least frequently used half of the elements if the process is under memory pressure
+func (l *cacheImpl[K, V]) shed() {
+	if !l.ext.pressure.pressured.Swap(false) {
+		return
+	}
+	for range (l.elemList.Size() + 1) / 2 {
+		l.evict()
+	}
+}
+
+// beforeOp runs the hooks preceding Get and Put: records the operation, sheds elements under memory pressure,
+// applies finished prefetches, reports the statistics and removes the entry of the key if it has expired.
+// Called only if hooked is set
+func (l *cacheImpl[K, V]) beforeOp(op byte, key K) {
+	x := l.ext
+	if x.recorder != nil {
+		x.recorder.record(op, key)
+	}
+	if x.ttl > 0 {
+		l.expire(key, l.clock())
+	}
+	if x.pressure != nil {
+		l.shed()
+	}
+	if x.prefetched != nil {
+		l.applyPrefetched()
+	}
+	if x.reporter != nil {
+		l.reportStats()
 	}
 }
 
 func (l *cacheImpl[K, V]) Get(key K) (V, error) {
+	if l.ext != nil && l.ext.hooked {
+		l.beforeOp(RecordGet, key)
+	}
 	if link, ok := l.keyToElement[key]; ok {
 		l.increaseFreq(link)
 		return link.Value.value, nil
@@ -143,33 +501,204 @@ func (l *cacheImpl[K, V]) Get(key K) (V, error) {
 }
 
 func (l *cacheImpl[K, V]) Put(key K, value V) {
+	l.put(key, value)
+}
+
+// TryPut works like Put, but returns ErrValueTooHeavy if the value is rejected because its weight
+// exceeds the limit set by WithMaxValueWeight or WithMaxWeight, or an error wrapping ErrCallbackPanic
+// if it is rejected because the weigher panics. The previous value of the key is removed then.
+//
+// O(1)
+func (l *cacheImpl[K, V]) TryPut(key K, value V) error {
+	_, err := l.put(key, value)
+	return err
+}
+
+func (l *cacheImpl[K, V]) PutWithEvicted(key K, value V) (K, V, bool) {
+	if victim, _ := l.put(key, value); victim != nil {
+		return victim.key, victim.value, true
+	}
+	var zero K
+	return zero, l.defaultValue, false
+}
+
+// put updates or inserts the key and returns the element evicted to make room for it, or nil.
+// Returns ErrValueTooHeavy if the value is rejected because of its weight.
+// Notifications are delivered once the cache is updated
+func (l *cacheImpl[K, V]) put(key K, value V) (*element[K, V], error) {
+	if l.ext == nil {
+		return l.putWeighed(key, value, 0), nil
+	}
+	l.begin()
+	victim, err := l.admit(key, value)
+	l.end()
+	return victim, err
+}
+
+// admit works like put, but leaves delivering notifications to the caller
+func (l *cacheImpl[K, V]) admit(key K, value V) (*element[K, V], error) {
+	x := l.ext
+	if x != nil && x.hooked {
+		l.beforeOp(RecordPut, key)
+	}
+	var weight int64
+	if x != nil && x.weigher != nil {
+		var err error
+		weight, err = l.weigh(key, value)
+		if err == nil && l.tooHeavy(key, weight) {
+			err = ErrValueTooHeavy
+		}
+		if err != nil {
+			l.record(statRejection)
+			if link, ok := l.keyToElement[key]; ok {
+				l.remove(link, ReasonEvicted)
+				if x.replicate != nil {
+					l.replicateOp(Op[K, V]{Kind: OpRemove, Key: key})
+				}
+			}
+			return nil, err
+		}
+	}
+	return l.putWeighed(key, value, weight), nil
+}
+
+// putWeighed updates or inserts the key with the value of the given weight, which fits in the cache,
+// and returns the element evicted to make room for it, or nil. Notifications have to be postponed by the caller
+func (l *cacheImpl[K, V]) putWeighed(key K, value V, weight int64) *element[K, V] {
+	x := l.ext
 	if link, ok := l.keyToElement[key]; ok {
 		l.increaseFreq(link)
+		old := link.Value.value
+		if x == nil {
+			link.Value.value = value
+			l.changed(key, false)
+			return nil
+		}
+		if x.interned != nil {
+			x.interned.release(old)
+			value = x.interned.intern(value)
+		}
 		link.Value.value = value
-		return
+		if x.ttl > 0 {
+			x.states[key].expires = l.clock() + x.ttl
+		}
+		if x.indexes != nil {
+			l.indexed(key, old, false)
+			l.indexed(key, value, true)
+		}
+		var victim *element[K, V]
+		if x.weigher != nil {
+			state := x.states[key]
+			l.weight += weight - state.weight
+			if x.namespaces != nil {
+				x.namespaces.add(key, 0, weight-state.weight)
+				victim = l.evictNamespaceWeight(key, 0, link)
+			}
+			state.weight = weight
+			if first := l.evictWeight(0, link); victim == nil {
+				victim = first
+			}
+		}
+		l.changed(key, false)
+		if x.replicate != nil {
+			l.replicateOp(Op[K, V]{Kind: OpPut, Key: key, Value: value})
+		}
+		if l.hasCallbacks() {
+			entry := l.entry(link.Value)
+			entry.Value = old
+			l.notify(entry, ReasonReplaced)
+		}
+		return victim
+	}
+	if l.capacity == 0 {
+		return nil
 	}
 
-	if l.elemList.Size() == l.capacity {
-		last := l.elemList.Back()
-		freq := last.Value.freq
-		l.freqToCount[freq]--
-
-		if l.freqToStart[freq] == last {
-			l.deleteBlock(freq)
+	var victim *element[K, V]
+	if link := l.namespaceVictim(key); link != nil {
+		victim = l.evictForNamespace(link, key)
+	} else if l.elemList.Size() == l.capacity {
+		victim = l.evictLink(l.victim())
+	}
+	if x != nil && x.weigher != nil {
+		if x.namespaces != nil {
+			if first := l.evictNamespaceWeight(key, weight, nil); victim == nil {
+				victim = first
+			}
+		}
+		if first := l.evictWeight(weight, nil); victim == nil {
+			victim = first
 		}
-		delete(l.keyToElement, last.Value.key)
-		l.elemList.Pop()
 	}
 
+	if x != nil && x.interned != nil {
+		value = x.interned.intern(value)
+	}
+	elem := &element[K, V]{key: key, value: value, freq: 1}
+	if x != nil && x.states != nil {
+		l.addState(key, 1, weight)
+	}
+	at := l.elemList.Head()
 	if start, ok := l.freqToStart[1]; ok {
-		l.keyToElement[key] = l.elemList.Push(&element[K, V]{key: key, value: value, freq: 1}, start)
+		at = start
 		l.freqToCount[1]++
 	} else {
-		l.keyToElement[key] = l.elemList.Push(&element[K, V]{key: key, value: value, freq: 1}, l.elemList.Head())
+		// transient elements are the last ones, so the block of frequency 0 is looked up only if it exists
+		if back := l.elemList.Back(); back != nil && back.Value.freq == 0 {
+			at = l.freqToStart[0]
+		}
 		l.freqToCount[1] = 1
 	}
+	link := l.elemList.InsertBefore(elem, at)
+	l.keyToElement[key] = link
+	l.freqToStart[1] = link
+	l.weight += weight
+	l.changed(key, false)
+	if x == nil {
+		return victim
+	}
+	if x.namespaces != nil {
+		x.namespaces.add(key, 1, weight)
+	}
+	if x.indexes != nil {
+		l.indexed(key, value, true)
+	}
+	if x.replicate != nil {
+		l.replicateOp(Op[K, V]{Kind: OpPut, Key: key, Value: value})
+	}
+	return victim
+}
+
+func (l *cacheImpl[K, V]) Remove(key K) error {
+	x := l.ext
+	if x != nil && x.recorder != nil {
+		x.recorder.record(RecordRemove, key)
+	}
+	link, ok := l.keyToElement[key]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	l.remove(link, ReasonRemoved)
+	if x != nil && x.replicate != nil {
+		l.replicateOp(Op[K, V]{Kind: OpRemove, Key: key})
+	}
+	return nil
+}
+
+func (l *cacheImpl[K, V]) GetMulti(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, err := l.Get(key); err == nil {
+			values[key] = value
+		}
+	}
+	return values
+}
 
-	l.freqToStart[1] = l.keyToElement[key]
+func (l *cacheImpl[K, V]) PutMulti(entries map[K]V) {
+	for key, value := range entries {
+		l.put(key, value)
+	}
 }
 
 func (l *cacheImpl[K, V]) All() iter.Seq2[K, V] {
@@ -182,6 +711,31 @@ func (l *cacheImpl[K, V]) All() iter.Seq2[K, V] {
 	}
 }
 
+// EvictionOrder returns the iterator in the order the entries would be evicted: in ascending order
+// of frequency, the least recently used key first if two or more keys have the same frequency,
+// even if the victim among them is chosen randomly because of WithRandomTieBreak.
+// It is the reverse of All, unless WithSampleRate is set: then the accesses counted but not applied yet
+// are included, like they are when the entries are evicted.
+//
+// O(capacity), or O(capacity * log(capacity)) if WithSampleRate is set
+func (l *cacheImpl[K, V]) EvictionOrder() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if l.ext != nil && l.ext.sampleRate > 0 {
+			for _, elem := range l.evictionOrder() {
+				if !yield(elem.key, elem.value) {
+					return
+				}
+			}
+			return
+		}
+		for elem := range l.elemList.Backward() {
+			if !yield(elem.key, elem.value) {
+				return
+			}
+		}
+	}
+}
+
 func (l *cacheImpl[K, V]) Size() int {
 	return l.elemList.Size()
 }
@@ -190,9 +744,94 @@ func (l *cacheImpl[K, V]) Capacity() int {
 	return l.capacity
 }
 
+// Available returns the number of entries that can be inserted without eviction.
+//
+// O(1)
+func (l *cacheImpl[K, V]) Available() int {
+	return l.capacity - l.elemList.Size()
+}
+
+// Weight returns the total weight of the entries, or 0 unless WithMaxWeight is set.
+//
+// O(1)
+func (l *cacheImpl[K, V]) Weight() int64 {
+	return l.weight
+}
+
+// MaxWeight returns the maximum total weight of the entries, or 0 unless WithMaxWeight is set.
+//
+// O(1)
+func (l *cacheImpl[K, V]) MaxWeight() int64 {
+	if l.ext == nil {
+		return 0
+	}
+	return l.ext.maxWeight
+}
+
+func (l *cacheImpl[K, V]) Clear() {
+	var cleared []Entry[K, V]
+	if l.hasCallbacks() {
+		cleared = make([]Entry[K, V], 0, l.elemList.Size())
+		for elem := range l.elemList.All() {
+			cleared = append(cleared, l.entry(elem))
+		}
+	}
+	l.elemList.Clear()
+	clear(l.keyToElement)
+	clear(l.freqToStart)
+	clear(l.freqToCount)
+	l.weight = 0
+	x := l.ext
+	if x != nil {
+		clear(x.meta)
+		clear(x.states)
+		for _, index := range x.indexes {
+			index.clear()
+		}
+		if x.interned != nil {
+			x.interned.clear()
+		}
+		if x.namespaces != nil {
+			clear(x.namespaces.sizes)
+			clear(x.namespaces.weights)
+		}
+	}
+	l.cleared()
+	if x != nil && x.replicate != nil {
+		l.replicateOp(Op[K, V]{Kind: OpClear})
+	}
+
+	for _, entry := range cleared {
+		l.notify(entry, ReasonCleared)
+	}
+}
+
+func (l *cacheImpl[K, V]) Stats() Stats {
+	var stats Stats
+	if l.stats != nil {
+		l.stats.counters(&stats)
+	}
+	stats.Blocks = len(l.freqToCount)
+	if l.elemList.Size() > 0 {
+		stats.LowestFrequency = l.elemList.Back().Value.freq
+		stats.LowestBlockSize = l.freqToCount[stats.LowestFrequency]
+		stats.HighestFrequency = l.elemList.Front().Value.freq
+		stats.HighestBlockSize = l.freqToCount[stats.HighestFrequency]
+	}
+	if x := l.ext; x != nil {
+		if x.namespaces != nil {
+			stats.Borrowed, _ = x.namespaces.usage()
+		}
+		if x.interned != nil {
+			stats.InternedValues = x.interned.size
+		}
+	}
+	return stats
+}
+
 func (l *cacheImpl[K, V]) GetKeyFrequency(key K) (int, error) {
 	if link, ok := l.keyToElement[key]; ok {
-		return link.Value.freq, nil
+		return l.frequency(link.Value), nil
 	}
 	return 0, ErrKeyNotFound
 }
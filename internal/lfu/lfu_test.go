@@ -1,25 +1,44 @@
 package lfu
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"hash/maphash"
+	"io"
 	"iter"
+	"math"
 	"math/rand/v2"
+	"runtime"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 	"unsafe"
 
+	"lfucache/internal/linkedlist"
+
 	"github.com/stretchr/testify/require"
 )
 
 // must compile
-func testImplements[K comparable, V any]() Cache[K, V] {
+func testImplements[K comparable, V any]() Replica[K, V] {
 	return New[K, V](1)
 }
 
+// must compile
+func testSampledImplements[K comparable, V any]() Replica[K, V] {
+	cache, _ := NewSampled[K, V](1, DefaultSamples, 0, nil)
+	return cache
+}
+
 func TestWithoutInvalidation(t *testing.T) {
 	t.Parallel()
 
 	cache := New[int, int](3)
 	require.Equal(t, unsafe.Sizeof((*int)(nil)), unsafe.Sizeof(cache))
+	require.Equal(t, 3*unsafe.Sizeof(0), unsafe.Sizeof(element[int, int]{}))
 
 	cache.Put(1, 1)
 	cache.Put(2, 4)
@@ -75,6 +94,129 @@ func TestGetPutPerformance(t *testing.T) {
 	require.LessOrEqual(t, float64(cache.NsPerOp())/float64(emulator.NsPerOp()), 2.)
 }
 
+// baselineLFU is the cache before the optional features were added: elements hold only the key, the value
+// and the frequency, and Get and Put update the list and the maps directly
+type baselineLFU struct {
+	elemList     linkedlist.List[*baselineElement]
+	keyToElement map[int]*linkedlist.Node[*baselineElement]
+	freqToStart  map[int]*linkedlist.Node[*baselineElement]
+	freqToCount  map[int]int
+	capacity     int
+}
+
+type baselineElement struct {
+	key, value, freq int
+}
+
+func newBaselineLFU(capacity int) *baselineLFU {
+	return &baselineLFU{
+		elemList:     linkedlist.New[*baselineElement](),
+		keyToElement: make(map[int]*linkedlist.Node[*baselineElement], capacity),
+		freqToStart:  make(map[int]*linkedlist.Node[*baselineElement], capacity),
+		freqToCount:  make(map[int]int, capacity),
+		capacity:     capacity,
+	}
+}
+
+func (l *baselineLFU) increaseFreq(link *linkedlist.Node[*baselineElement]) {
+	freq := link.Value.freq
+	l.freqToCount[freq]--
+	if l.freqToCount[freq] == 0 {
+		delete(l.freqToCount, freq)
+		delete(l.freqToStart, freq)
+	} else if l.freqToStart[freq] == link {
+		l.freqToStart[freq] = link.Next()
+	}
+	link.Value.freq++
+
+	if _, ok := l.freqToStart[freq+1]; ok {
+		l.elemList.Move(link, l.freqToStart[freq+1])
+		l.freqToStart[freq+1] = link
+		l.freqToCount[freq+1]++
+		return
+	}
+	l.freqToStart[freq+1] = link
+	l.freqToCount[freq+1] = 1
+	if next, ok := l.freqToStart[freq]; ok {
+		l.elemList.Move(link, next)
+	}
+}
+
+func (l *baselineLFU) Get(key int) (int, error) {
+	if link, ok := l.keyToElement[key]; ok {
+		l.increaseFreq(link)
+		return link.Value.value, nil
+	}
+	return 0, ErrKeyNotFound
+}
+
+func (l *baselineLFU) Put(key, value int) {
+	if link, ok := l.keyToElement[key]; ok {
+		l.increaseFreq(link)
+		link.Value.value = value
+		return
+	}
+	if l.elemList.Size() == l.capacity {
+		last := l.elemList.Back()
+		freq := last.Value.freq
+		l.freqToCount[freq]--
+		if l.freqToStart[freq] == last {
+			delete(l.freqToCount, freq)
+			delete(l.freqToStart, freq)
+		}
+		delete(l.keyToElement, last.Value.key)
+		l.elemList.Pop()
+	}
+	if start, ok := l.freqToStart[1]; ok {
+		l.keyToElement[key] = l.elemList.Push(&baselineElement{key: key, value: value, freq: 1}, start)
+		l.freqToCount[1]++
+	} else {
+		l.keyToElement[key] = l.elemList.Push(&baselineElement{key: key, value: value, freq: 1}, l.elemList.Head())
+		l.freqToCount[1] = 1
+	}
+	l.freqToStart[1] = l.keyToElement[key]
+}
+
+// defaultPathWorkload runs Put and Get of new keys and a random mix of hits and misses against a cache
+func defaultPathWorkload[C interface {
+	Get(int) (int, error)
+	Put(int, int)
+}](newCache func(int) C) func(*testing.B) {
+	keys := make([]int, 4096)
+	r := rand.New(rand.NewPCG(1, 1))
+	for i := range keys {
+		keys[i] = r.IntN(2048)
+	}
+	return func(b *testing.B) {
+		small, large := newCache(100), newCache(1024)
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := range b.N {
+			small.Put(i, i)
+			_, _ = small.Get(i - 1)
+			key := keys[i%len(keys)]
+			if _, err := large.Get(key); err != nil {
+				large.Put(key, i)
+			}
+		}
+	}
+}
+
+// TestDefaultPathPerformance compares a cache without options with the baseline cache it grew from,
+// so the optional features cost nothing unless they are enabled. The fastest of several runs is taken
+func TestDefaultPathPerformance(t *testing.T) {
+	workload := defaultPathWorkload(func(capacity int) *cacheImpl[int, int] { return New[int, int](capacity) })
+	baselineWorkload := defaultPathWorkload(newBaselineLFU)
+	cache, baseline := int64(math.MaxInt64), int64(math.MaxInt64)
+	for range 3 {
+		cache = min(cache, testing.Benchmark(workload).NsPerOp())
+		baseline = min(baseline, testing.Benchmark(baselineWorkload).NsPerOp())
+	}
+
+	require.LessOrEqual(t, float64(cache)/float64(baseline), 1.1)
+}
+
 func TestIteratorOrder(t *testing.T) {
 	cache := New[int, int](100)
 
@@ -513,6 +655,2474 @@ func TestAllIterator(t *testing.T) {
 	require.Equal(t, []int{50, 40, 30, 20, 10}, values)
 }
 
+func TestNewWithOptionsInvalidCapacity(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions[int, int](-1)
+	require.ErrorIs(t, err, ErrInvalidCapacity)
+}
+
+func TestMemoryPressureShedsEntries(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewWithOptions(10, WithMemoryPressure[int, int](1))
+	require.NoError(t, err)
+
+	for i := range 10 {
+		cache.Put(i, i)
+	}
+	_, _ = cache.Get(9)
+
+	require.Eventually(t, func() bool {
+		runtime.GC()
+		_, _ = cache.Get(-1)
+		return cache.Size() < 10
+	}, 5*time.Second, 10*time.Millisecond)
+
+	value, err := cache.Get(9)
+	require.NoError(t, err)
+	require.Equal(t, 9, value)
+}
+
+func TestClear(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](3)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	_, _ = cache.Get(2)
+
+	cache.Clear()
+	require.Equal(t, 0, cache.Size())
+	require.Equal(t, 3, cache.Capacity())
+
+	_, err := cache.Get(2)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	cache.Put(3, 30)
+	cache.Put(4, 40)
+
+	keys, values := collect(cache.All())
+	require.Equal(t, []int{4, 3}, keys)
+	require.Equal(t, []int{40, 30}, values)
+}
+
+func TestFrequencySaturation(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(3, WithMaxFrequency[int, int](0))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(3, WithMaxFrequency[int, int](2))
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(1)
+
+	freq, err := cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 2, freq)
+	require.Equal(t, uint64(2), cache.Stats().Saturations)
+
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{1, 2}, keys)
+
+	_, _ = cache.Get(2)
+	keys, _ = collect(cache.All())
+	require.Equal(t, []int{2, 1}, keys)
+	require.Equal(t, uint64(3), cache.Stats().Saturations)
+}
+
+func TestZeroCapacity(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](0)
+
+	require.NotPanics(t, func() {
+		cache.Put(1, 10)
+	})
+	require.Equal(t, 0, cache.Size())
+
+	_, err := cache.Get(1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestNilOption(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions[int, int](1, nil)
+	require.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestRandomOperationsNeverPanic(t *testing.T) {
+	t.Parallel()
+
+	for capacity := range 5 {
+		cache, err := NewWithOptions(capacity, WithMaxFrequency[int, int](3))
+		require.NoError(t, err)
+
+		require.NotPanics(t, func() {
+			for range 1000 {
+				key := rand.N(8)
+				switch rand.N(5) {
+				case 0:
+					cache.Put(key, key)
+				case 1:
+					_, _ = cache.Get(key)
+				case 2:
+					_, _ = cache.GetKeyFrequency(key)
+				case 3:
+					_, _ = collect(cache.All())
+				default:
+					if rand.N(50) == 0 {
+						cache.Clear()
+					}
+				}
+			}
+		})
+		require.LessOrEqual(t, cache.Size(), capacity)
+		require.NoError(t, cache.CheckInvariants())
+	}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](4)
+	for range 100 {
+		key := rand.N(6)
+		cache.Put(key, key)
+		_, _ = cache.Get(rand.N(6))
+		require.NoError(t, cache.CheckInvariants())
+	}
+
+	cache.elemList.Front().Value.freq = 0
+	require.ErrorIs(t, cache.CheckInvariants(), ErrInvariantViolated)
+
+	cache = New[int, int](4)
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	cache.freqToCount[1]++
+	require.ErrorIs(t, cache.CheckInvariants(), ErrInvariantViolated)
+
+	cache = New[int, int](4)
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	cache.freqToStart[1] = cache.elemList.Back()
+	require.ErrorIs(t, cache.CheckInvariants(), ErrInvariantViolated)
+}
+
+func TestRandomTieBreakIsReproducible(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(3, WithRandomTieBreak[int, int](nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	survivors := func(seed uint64) []int {
+		cache, err := NewWithOptions(8, WithRandomTieBreak[int, int](rand.NewPCG(seed, seed)))
+		require.NoError(t, err)
+
+		for i := range 100 {
+			cache.Put(i, i)
+			require.NoError(t, cache.CheckInvariants())
+		}
+		keys, _ := collect(cache.All())
+		return keys
+	}
+
+	require.Equal(t, survivors(42), survivors(42))
+	require.NotEqual(t, survivors(1), survivors(2))
+}
+
+func TestPutWithEvicted(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, string](2)
+
+	_, _, evicted := cache.PutWithEvicted(1, "one")
+	require.False(t, evicted)
+	_, _, evicted = cache.PutWithEvicted(2, "two")
+	require.False(t, evicted)
+	_, _ = cache.Get(1)
+
+	key, value, evicted := cache.PutWithEvicted(3, "three")
+	require.True(t, evicted)
+	require.Equal(t, 2, key)
+	require.Equal(t, "two", value)
+
+	_, _, evicted = cache.PutWithEvicted(3, "drei")
+	require.False(t, evicted)
+}
+
+func TestGetMultiPutMulti(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](4)
+
+	cache.PutMulti(map[int]int{1: 10, 2: 20, 3: 30})
+	require.Equal(t, 3, cache.Size())
+
+	values := cache.GetMulti([]int{1, 3, 5})
+	require.Equal(t, map[int]int{1: 10, 3: 30}, values)
+
+	freq, err := cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 2, freq)
+
+	require.Empty(t, cache.GetMulti(nil))
+}
+
+func TestRemove(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](3)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	cache.Put(3, 30)
+	_, _ = cache.Get(2)
+
+	require.NoError(t, cache.Remove(2))
+	require.ErrorIs(t, cache.Remove(2), ErrKeyNotFound)
+	require.NoError(t, cache.Remove(1))
+	require.NoError(t, cache.CheckInvariants())
+
+	cache.Put(4, 40)
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{4, 3}, keys)
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](3)
+	cache.Put(1, 10)
+
+	err := cache.Apply([]Op[int, int]{
+		{Kind: OpPut, Key: 2, Value: 20},
+		{Kind: OpRemove, Key: 1},
+		{Kind: OpRemove, Key: 5},
+		{Kind: OpPut, Key: 2, Value: 22},
+	})
+	require.NoError(t, err)
+
+	keys, values := collect(cache.All())
+	require.Equal(t, []int{2}, keys)
+	require.Equal(t, []int{22}, values)
+
+	err = cache.Apply([]Op[int, int]{
+		{Kind: OpRemove, Key: 2},
+		{Kind: OpKind(42), Key: 3},
+	})
+	require.ErrorIs(t, err, ErrInvalidOp)
+	require.Equal(t, 1, cache.Size())
+}
+
+func TestApplyIsOneUnit(t *testing.T) {
+	t.Parallel()
+
+	var (
+		cache    *cacheImpl[int, int]
+		sizes    []int
+		batches  [][]Entry[int, int]
+		streamed []Op[int, int]
+	)
+	cache, err := NewWithOptions(2,
+		WithMaxWeight[int, int](100, func(_, value int) int64 { return int64(value) }),
+		WithOnEvict(func(Entry[int, int], Reason) { sizes = append(sizes, cache.Size()) }),
+		WithOnEvictBatch(func(evicted []Entry[int, int]) { batches = append(batches, evicted) }),
+		WithReplication(func(op Op[int, int]) { streamed = append(streamed, op) }))
+	require.NoError(t, err)
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	streamed = nil
+
+	err = cache.Apply([]Op[int, int]{
+		{Kind: OpPut, Key: 3, Value: 30},
+		{Kind: OpRemove, Key: 2},
+		{Kind: OpPut, Key: 4, Value: 40},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 2}, sizes)
+	require.Equal(t, [][]Entry[int, int]{{{Key: 1, Value: 10, Frequency: 1}}}, batches)
+	require.Equal(t, []Op[int, int]{
+		{Kind: OpPut, Key: 3, Value: 30},
+		{Kind: OpRemove, Key: 2},
+		{Kind: OpPut, Key: 4, Value: 40},
+	}, streamed)
+
+	err = cache.Apply([]Op[int, int]{
+		{Kind: OpRemove, Key: 3},
+		{Kind: OpPut, Key: 5, Value: 500},
+	})
+	require.ErrorIs(t, err, ErrValueTooHeavy)
+	keys, _ := collect(cache.All())
+	require.ElementsMatch(t, []int{3, 4}, keys)
+	require.Len(t, streamed, 3)
+}
+
+func TestLoadMulti(t *testing.T) {
+	t.Parallel()
+
+	_, err := New[int, int](3).LoadMulti(context.Background(), []int{1})
+	require.ErrorIs(t, err, ErrNoLoader)
+
+	var calls [][]int
+	loader := BulkLoaderFunc[int, int](func(_ context.Context, keys []int) (map[int]int, error) {
+		calls = append(calls, keys)
+		values := make(map[int]int, len(keys))
+		for _, key := range keys {
+			if key != 4 {
+				values[key] = key * 10
+			}
+		}
+		return values, nil
+	})
+
+	cache, err := NewWithOptions(5, WithBulkLoader[int, int](loader))
+	require.NoError(t, err)
+	cache.Put(1, 11)
+
+	values, err := cache.LoadMulti(context.Background(), []int{1, 2, 3, 2, 4})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 11, 2: 20, 3: 30}, values)
+	require.Equal(t, [][]int{{2, 3, 4}}, calls)
+	require.Equal(t, 3, cache.Size())
+
+	values, err = cache.LoadMulti(context.Background(), []int{2, 3})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{2: 20, 3: 30}, values)
+	require.Len(t, calls, 1)
+
+	failing := BulkLoaderFunc[int, int](func(context.Context, []int) (map[int]int, error) {
+		return nil, context.Canceled
+	})
+	cache, err = NewWithOptions(5, WithBulkLoader[int, int](failing))
+	require.NoError(t, err)
+	cache.Put(1, 10)
+
+	values, err = cache.LoadMulti(context.Background(), []int{1, 2})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, map[int]int{1: 10}, values)
+}
+
+type eviction struct {
+	key    int
+	value  int
+	reason Reason
+}
+
+func TestCoalescingLoader(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewCoalescingLoader[int, int](nil, time.Millisecond)
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var (
+		mu    sync.Mutex
+		calls [][]int
+	)
+	release := make(chan struct{})
+	backend := BulkLoaderFunc[int, int](func(_ context.Context, keys []int) (map[int]int, error) {
+		mu.Lock()
+		calls = append(calls, slices.Sorted(slices.Values(keys)))
+		mu.Unlock()
+		<-release
+		values := make(map[int]int, len(keys))
+		for _, key := range keys {
+			values[key] = key * 10
+		}
+		return values, nil
+	})
+	loader, err := NewCoalescingLoader[int, int](backend, 100*time.Millisecond)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([]map[int]int, 3)
+	for i, keys := range [][]int{{1, 2}, {2, 3}, {3}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = loader.LoadMany(context.Background(), keys)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(calls) == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = loader.LoadMany(ctx, []int{1})
+	require.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	wg.Wait()
+	require.Equal(t, [][]int{{1, 2, 3}}, calls)
+	require.Equal(t, []map[int]int{{1: 10, 2: 20}, {2: 20, 3: 30}, {3: 30}}, results)
+
+	values, err := loader.LoadMany(context.Background(), []int{4})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{4: 40}, values)
+	require.Len(t, calls, 2)
+}
+
+func TestEvictionCallbacks(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithOnEvict[int, int](nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var global, local []eviction
+	cache, err := NewWithOptions(2, WithOnEvict(func(e Entry[int, int], r Reason) {
+		global = append(global, eviction{e.Key, e.Value, r})
+	}))
+	require.NoError(t, err)
+	onEvict := func(e Entry[int, int], r Reason) {
+		local = append(local, eviction{e.Key, e.Value, r})
+	}
+
+	cache.PutWithCallback(1, 10, onEvict)
+	cache.PutWithCallback(2, 20, onEvict)
+	cache.Put(2, 22)
+	cache.Put(3, 30)
+	require.NoError(t, cache.Remove(2))
+	cache.PutWithCallback(4, 40, onEvict)
+	cache.Clear()
+	cache.Put(4, 44)
+	cache.Clear()
+
+	require.Equal(t, []eviction{
+		{2, 20, ReasonReplaced},
+		{1, 10, ReasonEvicted},
+		{4, 40, ReasonCleared},
+	}, local)
+	require.Equal(t, []eviction{
+		{2, 20, ReasonReplaced},
+		{1, 10, ReasonEvicted},
+		{2, 22, ReasonRemoved},
+		{4, 40, ReasonCleared},
+		{3, 30, ReasonCleared},
+		{4, 44, ReasonCleared},
+	}, global)
+	require.Equal(t, "replaced", ReasonReplaced.String())
+}
+
+func TestCallbacksSeeUpdatedCache(t *testing.T) {
+	t.Parallel()
+
+	var (
+		cache *cacheImpl[int, int]
+		seen  []bool
+	)
+	cache, err := NewWithOptions(1, WithOnEvict(func(e Entry[int, int], _ Reason) {
+		_, err := cache.EntryInfo(2)
+		seen = append(seen, err == nil && cache.Size() == 1)
+		require.NoError(t, cache.CheckInvariants())
+	}))
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	cache.PutTransient(3, 30)
+	cache.Put(2, 22)
+	require.Equal(t, []bool{true, true}, seen)
+}
+
+func TestWarmFrom(t *testing.T) {
+	t.Parallel()
+
+	src := New[int, int](5)
+	for i := 1; i <= 5; i++ {
+		src.Put(i, i*10)
+		for range i {
+			_, _ = src.Get(i)
+		}
+	}
+
+	dst := New[int, int](4)
+	dst.Put(7, 70)
+	dst.Put(8, 80)
+	_, _ = dst.Get(8)
+
+	copied, err := dst.WarmFrom(src, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, copied)
+	require.NoError(t, dst.CheckInvariants())
+
+	keys, values := collect(dst.All())
+	require.Equal(t, []int{5, 4, 3, 8}, keys)
+	require.Equal(t, []int{50, 40, 30, 80}, values)
+
+	freq, err := dst.GetKeyFrequency(4)
+	require.NoError(t, err)
+	require.Equal(t, 5, freq)
+
+	srcKeys, _ := collect(src.All())
+	require.Equal(t, []int{5, 4, 3, 2, 1}, srcKeys)
+
+	full := New[int, int](1)
+	full.Put(9, 90)
+	for range 10 {
+		_, _ = full.Get(9)
+	}
+	copied, err = full.WarmFrom(src, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, copied)
+	require.NoError(t, full.CheckInvariants())
+
+	_, err = full.WarmFrom(nil, 0)
+	require.ErrorIs(t, err, ErrNilSource)
+
+	remote := struct{ WarmSource[int, int] }{src}
+	copied, err = New[int, int](2).WarmFrom(remote, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, copied)
+
+	unsorted := listedSource{{1, 1}, {2, 1}, {3, 100}, {4, 1}, {5, 50}, {6, 50}}
+	hot := New[int, int](3)
+	copied, err = hot.WarmFrom(unsorted, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, copied)
+	keys, _ = collect(hot.All())
+	require.Equal(t, []int{3, 5, 6}, keys)
+
+	sampled, err := NewSampled[int, int](3, DefaultSamples, 0, nil)
+	require.NoError(t, err)
+	copied, err = sampled.WarmFrom(unsorted, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, copied)
+	keys, _ = collect(sampled.All())
+	require.ElementsMatch(t, []int{3, 5, 6}, keys)
+}
+
+// listedSource is a WarmSource yielding the listed keys with their frequencies regardless of their order
+type listedSource [][2]int
+
+func (s listedSource) All() iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for _, entry := range s {
+			if !yield(entry[0], entry[0]*10) {
+				return
+			}
+		}
+	}
+}
+
+func (s listedSource) GetKeyFrequency(key int) (int, error) {
+	for _, entry := range s {
+		if entry[0] == key {
+			return entry[1], nil
+		}
+	}
+	return 0, ErrKeyNotFound
+}
+
+func TestIncrementInterval(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithIncrementInterval[int, int](-time.Second))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(2, WithIncrementInterval[int, int](time.Millisecond))
+	require.NoError(t, err)
+	now := int64(0)
+	cache.clock = func() int64 {
+		return now
+	}
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(1)
+
+	freq, err := cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 1, freq)
+	require.Equal(t, uint64(2), cache.Stats().SuppressedIncrements)
+
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{1, 2}, keys)
+
+	now += time.Millisecond.Nanoseconds()
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(1)
+
+	freq, err = cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 2, freq)
+	require.Equal(t, uint64(3), cache.Stats().SuppressedIncrements)
+	require.NoError(t, cache.CheckInvariants())
+}
+
+func TestMonotonicClock(t *testing.T) {
+	t.Parallel()
+
+	clock := monotonicClock()
+	start := clock()
+	require.GreaterOrEqual(t, start, int64(0))
+	require.Less(t, start, time.Second.Nanoseconds())
+
+	time.Sleep(time.Millisecond)
+	require.GreaterOrEqual(t, clock()-start, time.Millisecond.Nanoseconds())
+}
+
+func TestPutWithMeta(t *testing.T) {
+	t.Parallel()
+
+	var evicted []Entry[string, int]
+	cache, err := NewWithOptions(2, WithOnEvict(func(e Entry[string, int], _ Reason) {
+		evicted = append(evicted, e)
+	}))
+	require.NoError(t, err)
+
+	cache.PutWithMeta("a", 1, "shard-1")
+	cache.Put("b", 2)
+	cache.Put("a", 11)
+
+	entry, err := cache.EntryInfo("a")
+	require.NoError(t, err)
+	require.Equal(t, Entry[string, int]{Key: "a", Value: 11, Frequency: 2, Meta: "shard-1"}, entry)
+
+	entry, err = cache.EntryInfo("b")
+	require.NoError(t, err)
+	require.Nil(t, entry.Meta)
+
+	_, err = cache.EntryInfo("c")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, cache.Remove("a"))
+	cache.Put("a", 3)
+
+	entry, err = cache.EntryInfo("a")
+	require.NoError(t, err)
+	require.Nil(t, entry.Meta)
+
+	require.Equal(t, []Entry[string, int]{
+		{Key: "a", Value: 1, Frequency: 2, Meta: "shard-1"},
+		{Key: "a", Value: 11, Frequency: 2, Meta: "shard-1"},
+	}, evicted)
+}
+
+func TestChangesSince(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithChangeHistory[int, int](0))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(2, WithChangeHistory[int, int](4))
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	start := cache.Generation()
+	require.Equal(t, uint64(1), start)
+
+	cache.Put(2, 20)
+	_, _ = cache.Get(2)
+	require.Equal(t, uint64(2), cache.Generation())
+
+	cache.Put(1, 11)
+	cache.Put(3, 30)
+
+	changes, err := cache.ChangesSince(start)
+	require.NoError(t, err)
+	require.Equal(t, []Change[int]{
+		{Key: 1, Generation: 3},
+		{Key: 2, Removed: true, Generation: 4},
+		{Key: 3, Generation: 5},
+	}, changes)
+
+	changes, err = cache.ChangesSince(cache.Generation())
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = cache.ChangesSince(0)
+	require.ErrorIs(t, err, ErrHistoryTruncated)
+	_, err = cache.ChangesSince(cache.Generation() + 1)
+	require.ErrorIs(t, err, ErrHistoryTruncated)
+
+	gen := cache.Generation()
+	cache.Clear()
+	_, err = cache.ChangesSince(gen)
+	require.ErrorIs(t, err, ErrHistoryTruncated)
+
+	_, err = New[int, int](2).ChangesSince(0)
+	require.NoError(t, err)
+}
+
+func TestBlockStats(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](10)
+	require.Equal(t, Stats{}, cache.Stats())
+
+	for i := range 6 {
+		cache.Put(i, i)
+	}
+	_, _ = cache.Get(0)
+	_, _ = cache.Get(0)
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(2)
+
+	stats := cache.Stats()
+	require.Equal(t, 3, stats.Blocks)
+	require.Equal(t, 1, stats.LowestFrequency)
+	require.Equal(t, 3, stats.LowestBlockSize)
+	require.Equal(t, 3, stats.HighestFrequency)
+	require.Equal(t, 1, stats.HighestBlockSize)
+}
+
+func TestLogFrequency(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewWithOptions(3, WithLogFrequency[int, int]())
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	cache.Put(3, 30)
+
+	expected := []int{1, 2, 2, 4, 4, 4, 4, 8, 8}
+	for _, freq := range expected {
+		actual, err := cache.GetKeyFrequency(1)
+		require.NoError(t, err)
+		require.Equal(t, freq, actual)
+		_, _ = cache.Get(1)
+		require.NoError(t, cache.CheckInvariants())
+	}
+
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(3)
+	_, _ = cache.Get(3)
+	_, _ = cache.Get(2)
+
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{1, 2, 3}, keys)
+	require.Equal(t, 2, cache.Stats().Blocks)
+
+	warmed, err := NewWithOptions(3, WithLogFrequency[int, int]())
+	require.NoError(t, err)
+	copied, err := warmed.WarmFrom(cache, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, copied)
+	require.NoError(t, warmed.CheckInvariants())
+}
+
+func TestMorrisCounter(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithMorrisCounter[int, int](0, nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(2,
+		WithMorrisCounter[int, int](10, rand.NewPCG(1, 2)),
+		WithMaxFrequency[int, int](255),
+	)
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	for range 10_000 {
+		_, _ = cache.Get(1)
+	}
+	_, _ = cache.Get(2)
+
+	freq, err := cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Greater(t, freq, 10)
+	require.Less(t, freq, 200)
+
+	freq, err = cache.GetKeyFrequency(2)
+	require.NoError(t, err)
+	require.Equal(t, 2, freq)
+
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{1, 2}, keys)
+	require.NoError(t, cache.CheckInvariants())
+}
+
+func TestSampledCache(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSampled[int, int](-1, DefaultSamples, 0, nil)
+	require.ErrorIs(t, err, ErrInvalidCapacity)
+	_, err = NewSampled[int, int](1, 0, 0, nil)
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewSampled[int, int](1, 1, -time.Second, nil)
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewSampled[int, int](4, 64, 0, rand.NewPCG(1, 1))
+	require.NoError(t, err)
+
+	for i := 1; i <= 4; i++ {
+		cache.Put(i, i*10)
+		for range i {
+			_, _ = cache.Get(i)
+		}
+	}
+	cache.Put(5, 50)
+	cache.Put(6, 60)
+
+	_, err = cache.Get(1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = cache.Get(5)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	keys, values := collect(cache.All())
+	require.Equal(t, []int{4, 3, 2, 6}, keys)
+	require.Equal(t, []int{40, 30, 20, 60}, values)
+	keys, _ = collect(cache.EvictionOrder())
+	require.Equal(t, []int{6, 2, 3, 4}, keys)
+	rank, err := cache.Rank(3)
+	require.NoError(t, err)
+	require.Equal(t, 2, rank)
+
+	freq, err := cache.GetKeyFrequency(4)
+	require.NoError(t, err)
+	require.Equal(t, 5, freq)
+	stats := cache.Stats()
+	require.Equal(t, 4, stats.Blocks)
+	require.Equal(t, 5, stats.HighestFrequency)
+	require.NoError(t, cache.CheckInvariants())
+
+	require.NoError(t, cache.Remove(3))
+	require.ErrorIs(t, cache.Remove(3), ErrKeyNotFound)
+	require.Equal(t, 3, cache.Size())
+	require.Equal(t, 4, cache.Capacity())
+	require.Equal(t, 1, cache.Available())
+
+	warmed := New[int, int](4)
+	copied, err := warmed.WarmFrom(cache, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, copied)
+	shadowed, err := NewShadowed[int, int](cache, nil)
+	require.NoError(t, err)
+	_, err = shadowed.Get(4)
+	require.NoError(t, err)
+
+	cache.Clear()
+	require.Equal(t, 0, cache.Size())
+	require.Zero(t, cache.Stats())
+}
+
+func TestSampledCallbacks(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewSampled[int, int](2, DefaultSamples, 0, rand.NewPCG(1, 1))
+	require.NoError(t, err)
+	var notified []Reason
+	var sizes []int
+	onEvict := func(_ Entry[int, int], reason Reason) {
+		notified = append(notified, reason)
+		sizes = append(sizes, cache.Size())
+	}
+	cache.PutWithCallback(1, 10, onEvict)
+	cache.PutWithCallback(2, 20, onEvict)
+
+	require.NoError(t, cache.Apply([]Op[int, int]{
+		{Kind: OpPut, Key: 1, Value: 11},
+		{Kind: OpRemove, Key: 2},
+		{Kind: OpPut, Key: 3, Value: 30},
+	}))
+	require.Equal(t, []Reason{ReasonReplaced, ReasonRemoved}, notified)
+	require.Equal(t, []int{2, 2}, sizes)
+
+	cache.PutWithCallback(3, 31, func(Entry[int, int], Reason) { panic("buggy callback") })
+	require.NoError(t, cache.Remove(3))
+	require.Equal(t, uint64(1), cache.Stats().CallbackPanics)
+}
+
+func TestSampledDecay(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewSampled[int, int](2, 16, time.Minute, rand.NewPCG(1, 1))
+	require.NoError(t, err)
+	now := int64(0)
+	cache.now = func() int64 {
+		return now
+	}
+
+	cache.Put(1, 10)
+	for range 4 {
+		_, _ = cache.Get(1)
+	}
+	freq, err := cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 5, freq)
+
+	now += 3 * time.Minute.Nanoseconds()
+	cache.Put(2, 20)
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(2)
+	freq, err = cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 2, freq)
+
+	cache.Put(3, 30)
+	_, err = cache.Get(1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{2, 3}, keys)
+
+	now += time.Hour.Nanoseconds()
+	freq, err = cache.GetKeyFrequency(2)
+	require.NoError(t, err)
+	require.Zero(t, freq)
+	_, _ = cache.Get(2)
+	freq, err = cache.GetKeyFrequency(2)
+	require.NoError(t, err)
+	require.Equal(t, 1, freq)
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	loader := BulkLoaderFunc[int, int](func(_ context.Context, keys []int) (map[int]int, error) {
+		<-release
+		values := make(map[int]int, len(keys))
+		for _, key := range keys {
+			values[key] = key * 10
+		}
+		return values, nil
+	})
+
+	_, err := NewWithOptions(5, WithPrefetchConcurrency[int, int](0))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(5, WithBulkLoader[int, int](loader), WithPrefetchConcurrency[int, int](1))
+	require.NoError(t, err)
+	cache.Put(1, 11)
+
+	cache.Prefetch(context.Background(), []int{1, 2, 3})
+	cache.Prefetch(context.Background(), []int{4})
+	_, err = cache.Get(2)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	cache.Put(3, 33)
+	close(release)
+	require.Eventually(t, func() bool {
+		_, err := cache.Get(2)
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	value, err := cache.Get(3)
+	require.NoError(t, err)
+	require.Equal(t, 33, value)
+	value, err = cache.Get(1)
+	require.NoError(t, err)
+	require.Equal(t, 11, value)
+	_, err = cache.Get(4)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Equal(t, 3, cache.Size())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(5, WithCircuitBreaker[int, int](0, 0.5, time.Second))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(5, WithCircuitBreaker[int, int](2, 1, time.Second))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var failure error
+	calls := 0
+	loader := BulkLoaderFunc[int, int](func(_ context.Context, keys []int) (map[int]int, error) {
+		calls++
+		if failure != nil {
+			return nil, failure
+		}
+		return map[int]int{keys[0]: keys[0] * 10}, nil
+	})
+	cache, err := NewWithOptions(5, WithBulkLoader[int, int](loader), WithCircuitBreaker[int, int](2, 0.5, time.Second))
+	require.NoError(t, err)
+	now := int64(0)
+	cache.clock = func() int64 {
+		return now
+	}
+	cache.Put(1, 10)
+	ctx := context.Background()
+
+	failure = errors.New("backend is down")
+	_, err = cache.LoadMulti(ctx, []int{2})
+	require.ErrorIs(t, err, failure)
+	_, err = cache.LoadMulti(ctx, []int{2})
+	require.ErrorIs(t, err, failure)
+	require.Equal(t, 2, calls)
+
+	values, err := cache.LoadMulti(ctx, []int{1, 2})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, map[int]int{1: 10}, values)
+	require.Equal(t, 2, calls)
+
+	now += time.Second.Nanoseconds()
+	_, err = cache.LoadMulti(ctx, []int{2})
+	require.ErrorIs(t, err, failure)
+	_, err = cache.LoadMulti(ctx, []int{2})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 3, calls)
+
+	now += time.Second.Nanoseconds()
+	failure = nil
+	values, err = cache.LoadMulti(ctx, []int{2})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{2: 20}, values)
+	values, err = cache.LoadMulti(ctx, []int{3})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{3: 30}, values)
+	require.Equal(t, 5, calls)
+}
+
+func TestNamespaceQuotas(t *testing.T) {
+	t.Parallel()
+
+	namespaceOf := func(key string) string {
+		return key[:1]
+	}
+	_, err := NewWithOptions(5, WithNamespaceQuotas[string, int](nil, nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(5, WithNamespaceQuotas[string, int](namespaceOf, map[string]int{"a": 0}))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(4, WithNamespaceQuotas[string, int](namespaceOf, map[string]int{"a": 2}))
+	require.NoError(t, err)
+
+	cache.Put("b1", 1)
+	cache.Put("a1", 1)
+	_, _ = cache.Get("a1")
+	cache.Put("a2", 2)
+	key, _, evicted := cache.PutWithEvicted("a3", 3)
+	require.True(t, evicted)
+	require.Equal(t, "a2", key)
+
+	cache.Put("b2", 2)
+	key, _, evicted = cache.PutWithEvicted("a4", 4)
+	require.True(t, evicted)
+	require.Equal(t, "a3", key)
+
+	keys, _ := collect(cache.All())
+	require.ElementsMatch(t, []string{"a1", "a4", "b1", "b2"}, keys)
+
+	require.NoError(t, cache.Remove("a4"))
+	cache.Put("a5", 5)
+	require.Equal(t, 4, cache.Size())
+	_, err = cache.Get("b1")
+	require.NoError(t, err)
+
+	cache.Clear()
+	cache.Put("a1", 1)
+	cache.Put("a2", 2)
+	require.Equal(t, 2, cache.Size())
+	require.NoError(t, cache.CheckInvariants())
+}
+
+func TestNamespaceMaxWeights(t *testing.T) {
+	t.Parallel()
+
+	namespaceOf := func(key string) string {
+		return key[:1]
+	}
+	weigher := func(_ string, value int) int64 {
+		return int64(value)
+	}
+	maxWeights := WithNamespaceMaxWeights[string, int](map[string]int64{"a": 10})
+	_, err := NewWithOptions(5, WithNamespaceMaxWeights[string, int](map[string]int64{"a": 0}))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(5, maxWeights, WithMaxWeight(100, weigher))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(5, maxWeights, WithNamespaceQuotas[string, int](namespaceOf, nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(10, maxWeights,
+		WithNamespaceQuotas[string, int](namespaceOf, nil), WithMaxWeight(100, weigher))
+	require.NoError(t, err)
+
+	cache.Put("b1", 20)
+	cache.Put("a1", 5)
+	cache.Put("a2", 5)
+	_, _ = cache.Get("a2")
+	key, _, evicted := cache.PutWithEvicted("a3", 4)
+	require.True(t, evicted)
+	require.Equal(t, "a1", key)
+	require.ErrorIs(t, cache.TryPut("a4", 11), ErrValueTooHeavy)
+
+	cache.Put("a3", 6)
+	keys, _ := collect(cache.All())
+	require.ElementsMatch(t, []string{"a3", "b1"}, keys)
+	require.Equal(t, int64(26), cache.Weight())
+
+	cache.Clear()
+	cache.Put("a1", 10)
+	require.Equal(t, 1, cache.Size())
+	require.NoError(t, cache.CheckInvariants())
+}
+
+func TestQuotaBorrowing(t *testing.T) {
+	t.Parallel()
+
+	namespaceOf := func(key string) string {
+		return key[:1]
+	}
+	cache, err := NewWithOptions(4,
+		WithNamespaceQuotas[string, int](namespaceOf, map[string]int{"a": 2, "b": 2}),
+		WithQuotaBorrowing[string, int]())
+	require.NoError(t, err)
+
+	for _, key := range []string{"a1", "a2", "a3", "a4"} {
+		_, _, evicted := cache.PutWithEvicted(key, 0)
+		require.False(t, evicted)
+	}
+	require.Equal(t, 2, cache.Stats().Borrowed)
+
+	key, _, evicted := cache.PutWithEvicted("a5", 0)
+	require.True(t, evicted)
+	require.Equal(t, "a1", key)
+
+	key, _, evicted = cache.PutWithEvicted("b1", 0)
+	require.True(t, evicted)
+	require.Equal(t, "a2", key)
+	require.Equal(t, 1, cache.Stats().Borrowed)
+
+	key, _, evicted = cache.PutWithEvicted("b2", 0)
+	require.True(t, evicted)
+	require.Equal(t, "a3", key)
+
+	stats := cache.Stats()
+	require.Equal(t, 0, stats.Borrowed)
+	require.Equal(t, uint64(2), stats.Reclaims)
+
+	keys, _ := collect(cache.All())
+	require.Equal(t, []string{"b2", "b1", "a5", "a4"}, keys)
+}
+
+func TestSoftLimit(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(3, WithSoftLimit[int, int](4))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(5, WithSoftLimit[int, int](2))
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		cache.Put(i, i)
+		_, _ = cache.Get(i)
+	}
+	require.Equal(t, 5, cache.Size())
+
+	key, _, evicted := cache.PutWithEvicted(6, 6)
+	require.True(t, evicted)
+	require.Equal(t, 1, key)
+
+	_, err = cache.Get(7)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Equal(t, 5, cache.Size())
+
+	require.Equal(t, 3, cache.Trim())
+	require.Equal(t, 0, cache.Trim())
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{5, 4}, keys)
+	require.Equal(t, 0, New[int, int](2).Trim())
+}
+
+func TestWeightedEviction(t *testing.T) {
+	t.Parallel()
+
+	weigher := func(_ string, value int) int64 {
+		return int64(value)
+	}
+	_, err := NewWithOptions(5, WithMaxWeight[string, int](0, weigher))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(5, WithOnEvictBatch[string, int](nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var batches [][]string
+	var evictions []string
+	cache, err := NewWithOptions(10,
+		WithMaxWeight[string, int](10, weigher),
+		WithOnEvict[string, int](func(entry Entry[string, int], reason Reason) {
+			if reason == ReasonEvicted {
+				evictions = append(evictions, entry.Key)
+			}
+		}),
+		WithOnEvictBatch[string, int](func(entries []Entry[string, int]) {
+			keys := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+			}
+			batches = append(batches, keys)
+		}))
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		cache.Put(key, 2)
+	}
+	_, _ = cache.Get("a")
+	require.Empty(t, batches)
+
+	key, _, evicted := cache.PutWithEvicted("big", 7)
+	require.True(t, evicted)
+	require.Equal(t, "b", key)
+	require.Equal(t, [][]string{{"b", "c", "d", "e"}}, batches)
+	require.Equal(t, []string{"b", "c", "d", "e"}, evictions)
+
+	cache.Put("huge", 11)
+	_, err = cache.Get("huge")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Len(t, batches, 1)
+
+	cache.Put("a", 4)
+	require.Equal(t, [][]string{{"b", "c", "d", "e"}, {"big"}}, batches)
+	keys, _ := collect(cache.All())
+	require.Equal(t, []string{"a"}, keys)
+
+	cache.Put("a", 11)
+	require.Equal(t, 0, cache.Size())
+	require.NoError(t, cache.CheckInvariants())
+}
+
+func TestTrimInBackground(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	_, err := NewWithOptions(10, WithBackgroundTrim[int, int](time.Millisecond, &mu))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(10, WithSoftLimit[int, int](3), WithBackgroundTrim[int, int](0, &mu))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(10, WithSoftLimit[int, int](3), WithBackgroundTrim[int, int](time.Millisecond, nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(10, WithSoftLimit[int, int](3), WithBackgroundTrim[int, int](time.Millisecond, &mu))
+	require.NoError(t, err)
+	mu.Lock()
+	for i := range 8 {
+		cache.Put(i, i)
+	}
+	require.Equal(t, 8, cache.Size())
+	mu.Unlock()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return cache.Size() == 3
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	require.NoError(t, cache.Close())
+	require.NoError(t, cache.Close())
+	for i := range 8 {
+		cache.Put(i, i)
+	}
+	mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	require.Equal(t, 8, cache.Size())
+	mu.Unlock()
+
+	inner, err := NewWithOptions(10, WithSoftLimit[int, int](2))
+	require.NoError(t, err)
+	synchronized, err := NewSynchronized[int, int](inner)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.ErrorIs(t, TrimInBackground(ctx, nil, synchronized, time.Millisecond), ErrInvalidOption)
+	require.ErrorIs(t, TrimInBackground(ctx, inner, nil, time.Millisecond), ErrInvalidOption)
+	require.NoError(t, TrimInBackground(ctx, inner, synchronized, time.Millisecond))
+	for i := range 5 {
+		synchronized.Put(i, i)
+	}
+	require.Eventually(t, func() bool {
+		synchronized.Lock()
+		defer synchronized.Unlock()
+		return inner.Size() == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestSampleRate(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(5, WithSampleRate[int, int](0))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(3, WithSampleRate[int, int](3))
+	require.NoError(t, err)
+	cache.Put(1, 1)
+	cache.Put(2, 2)
+	cache.Put(3, 3)
+	_, _ = cache.Get(3)
+	_, _ = cache.Get(3)
+	_, _ = cache.Get(2)
+
+	freq, err := cache.GetKeyFrequency(3)
+	require.NoError(t, err)
+	require.Equal(t, 3, freq)
+	keys, _ := collect(cache.All())
+	require.Equal(t, []int{3, 2, 1}, keys)
+
+	_, _ = cache.Get(3)
+	keys, _ = collect(cache.All())
+	require.Equal(t, []int{3, 2, 1}, keys)
+	require.Equal(t, 2, cache.Stats().Blocks)
+
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(1)
+	key, _, evicted := cache.PutWithEvicted(4, 4)
+	require.True(t, evicted)
+	require.Equal(t, 2, key)
+	keys, _ = collect(cache.All())
+	require.Equal(t, []int{3, 1, 4}, keys)
+
+	random := rand.New(rand.NewPCG(1, 2))
+	cache, err = NewWithOptions(20, WithSampleRate[int, int](4))
+	require.NoError(t, err)
+	for range 10000 {
+		key := random.IntN(40)
+		if random.IntN(2) == 0 {
+			cache.Put(key, key)
+		} else {
+			_, _ = cache.Get(key)
+		}
+		require.NoError(t, cache.CheckInvariants())
+	}
+}
+
+func TestAgeAndIdleTime(t *testing.T) {
+	t.Parallel()
+
+	_, err := New[int, int](2).Age(1)
+	require.ErrorIs(t, err, ErrNoTimestamps)
+	_, err = New[int, int](2).IdleTime(1)
+	require.ErrorIs(t, err, ErrNoTimestamps)
+
+	cache, err := NewWithOptions(2, WithTimestamps[int, int]())
+	require.NoError(t, err)
+	now := int64(0)
+	cache.clock = func() int64 {
+		return now
+	}
+
+	cache.Put(1, 1)
+	now += int64(time.Second)
+	_, _ = cache.Get(1)
+	now += int64(time.Second)
+	cache.Put(1, 2)
+	now += int64(time.Second)
+
+	age, err := cache.Age(1)
+	require.NoError(t, err)
+	require.Equal(t, 3*time.Second, age)
+	idle, err := cache.IdleTime(1)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, idle)
+
+	_, err = cache.Age(2)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = cache.IdleTime(2)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestTTL(t *testing.T) {
+	t.Parallel()
+
+	_, err := New[int, int](2).TTL(1)
+	require.ErrorIs(t, err, ErrNoTTL)
+	_, _, err = New[int, int](2).GetWithTTL(1)
+	require.ErrorIs(t, err, ErrNoTTL)
+	_, err = NewWithOptions(2, WithTTL[int, int](0))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var expired []int
+	cache, err := NewWithOptions(2, WithTTL[int, int](3*time.Second), WithOnEvict(func(e Entry[int, int], r Reason) {
+		if r == ReasonExpired {
+			expired = append(expired, e.Key)
+		}
+	}))
+	require.NoError(t, err)
+	now := int64(0)
+	cache.clock = func() int64 {
+		return now
+	}
+
+	cache.Put(1, 1)
+	cache.Put(2, 2)
+	now += int64(2 * time.Second)
+	ttl, err := cache.TTL(1)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, ttl)
+
+	cache.Put(1, 10)
+	value, ttl, err := cache.GetWithTTL(1)
+	require.NoError(t, err)
+	require.Equal(t, 10, value)
+	require.Equal(t, 3*time.Second, ttl)
+
+	now += int64(time.Second)
+	_, err = cache.Get(2)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = cache.TTL(2)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Equal(t, []int{2}, expired)
+	require.Equal(t, 1, cache.Size())
+	require.NoError(t, cache.CheckInvariants())
+
+	now += int64(2 * time.Second)
+	_, _, err = cache.GetWithTTL(1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Equal(t, []int{2, 1}, expired)
+	require.Equal(t, 0, cache.Size())
+	require.Equal(t, "expired", ReasonExpired.String())
+}
+
+func TestRank(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](5)
+	_, err := cache.Rank(1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	for i := 1; i <= 5; i++ {
+		cache.Put(i, i)
+	}
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(4)
+
+	ranks := make(map[int]int)
+	for i := 1; i <= 5; i++ {
+		rank, err := cache.Rank(i)
+		require.NoError(t, err)
+		ranks[i] = rank
+	}
+	require.Equal(t, map[int]int{1: 0, 3: 1, 5: 2, 4: 3, 2: 4}, ranks)
+
+	victim, _, evicted := cache.PutWithEvicted(6, 6)
+	require.True(t, evicted)
+	require.Equal(t, 1, victim)
+}
+
+func TestEvictionOrder(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](5)
+	for i := 1; i <= 5; i++ {
+		cache.Put(i, i*10)
+	}
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(4)
+
+	keys, values := collect(cache.EvictionOrder())
+	require.Equal(t, []int{1, 3, 5, 4, 2}, keys)
+	require.Equal(t, []int{10, 30, 50, 40, 20}, values)
+
+	allKeys, _ := collect(cache.All())
+	slices.Reverse(allKeys)
+	require.Equal(t, keys, allKeys)
+
+	for key := range cache.EvictionOrder() {
+		victim, _, evicted := cache.PutWithEvicted(6, 60)
+		require.True(t, evicted)
+		require.Equal(t, key, victim)
+		break
+	}
+}
+
+func TestEvictionOrderWithSampleRate(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewWithOptions(4, WithSampleRate[int, int](10))
+	require.NoError(t, err)
+	for i := 1; i <= 4; i++ {
+		cache.Put(i, i*10)
+	}
+	for range 5 {
+		_, _ = cache.Get(1)
+	}
+	_, _ = cache.Get(3)
+	_, _ = cache.Get(3)
+	_, _ = cache.Get(2)
+	_, _ = cache.Get(2)
+
+	keys, _ := collect(cache.EvictionOrder())
+	require.Equal(t, []int{4, 2, 3, 1}, keys)
+	for rank, key := range keys {
+		got, err := cache.Rank(key)
+		require.NoError(t, err)
+		require.Equal(t, rank, got)
+	}
+
+	for _, key := range keys[:3] {
+		victim, _, evicted := cache.PutWithEvicted(-key, 0)
+		require.True(t, evicted)
+		require.Equal(t, key, victim)
+		for range 10 {
+			_, _ = cache.Get(-key)
+		}
+	}
+}
+
+func TestShadowed(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewShadowed[int, int](nil, nil)
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewShadowed(New[int, int](2), map[string]Replica[int, struct{}]{"nil": nil})
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewShadowed(New[int, int](2), map[string]Replica[int, struct{}]{
+		"big": New[int, struct{}](4),
+	})
+	require.NoError(t, err)
+	var _ Replica[int, int] = cache
+
+	for i := 1; i <= 4; i++ {
+		cache.Put(i, i)
+	}
+	for i := 1; i <= 4; i++ {
+		_, _ = cache.Get(i)
+	}
+	primary, shadows := cache.HitStats()
+	require.Equal(t, HitStats{Hits: 2, Misses: 2}, primary)
+	require.Equal(t, map[string]HitStats{"big": {Hits: 4}}, shadows)
+	require.InDelta(t, 0.5, primary.HitRate(), 1e-9)
+	require.InDelta(t, 0.0, HitStats{}.HitRate(), 1e-9)
+
+	require.NoError(t, cache.Remove(4))
+	require.NoError(t, cache.Apply([]Op[int, int]{{Kind: OpRemove, Key: 1}}))
+	_, _ = cache.Get(4)
+	_, _ = cache.Get(1)
+	_, shadows = cache.HitStats()
+	require.Equal(t, HitStats{Hits: 4, Misses: 2}, shadows["big"])
+	require.Equal(t, 1, cache.Size())
+
+	stats := cache.Stats()
+	require.Equal(t, HitStats{Hits: 2, Misses: 4}, stats.Hits)
+	require.Equal(t, map[string]HitStats{"big": {Hits: 4, Misses: 2}}, stats.ShadowHits)
+	require.Equal(t, 1, stats.Blocks)
+}
+
+// countingSource counts the reads of the changes of the cache
+type countingSource struct {
+	*cacheImpl[int, int]
+	reads int
+}
+
+func (c *countingSource) ChangesSince(gen uint64) ([]Change[int], error) {
+	c.reads++
+	return c.cacheImpl.ChangesSince(gen)
+}
+
+func TestShadowedSyncReadsChangesOnce(t *testing.T) {
+	t.Parallel()
+
+	source, err := NewWithOptions(4, WithChangeHistory[int, int](2))
+	require.NoError(t, err)
+	counted := &countingSource{cacheImpl: source}
+	primary := New[int, int](4)
+	shadow := New[int, struct{}](4)
+	cache, err := NewShadowed(primary, map[string]Replica[int, struct{}]{"shadow": shadow})
+	require.NoError(t, err)
+
+	source.Put(1, 1)
+	source.Put(2, 2)
+	gen, err := cache.SyncFrom(counted, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, counted.reads)
+	keys, _ := collect(shadow.All())
+	require.Equal(t, []int{2, 1}, keys)
+
+	for i := 3; i <= 5; i++ {
+		source.Put(i, i)
+	}
+	_, err = cache.SyncFrom(counted, gen)
+	require.NoError(t, err)
+	require.Equal(t, 2, counted.reads)
+	keys, _ = collect(shadow.All())
+	primaryKeys, _ := collect(primary.All())
+	require.Equal(t, primaryKeys, keys)
+
+	_, err = cache.SyncFrom(nil, 0)
+	require.ErrorIs(t, err, ErrNilSource)
+}
+
+func TestShadowedMirrorsLoadsAndCopies(t *testing.T) {
+	t.Parallel()
+
+	loader := BulkLoaderFunc[int, int](func(_ context.Context, keys []int) (map[int]int, error) {
+		values := make(map[int]int, len(keys))
+		for _, key := range keys {
+			if key > 0 {
+				values[key] = key * 10
+			}
+		}
+		return values, nil
+	})
+	primary, err := NewWithOptions(2, WithBulkLoader[int, int](loader))
+	require.NoError(t, err)
+	shadow := New[int, struct{}](4)
+	cache, err := NewShadowed(primary, map[string]Replica[int, struct{}]{"big": shadow})
+	require.NoError(t, err)
+
+	values, err := cache.LoadMulti(context.Background(), []int{1, 2, 2, -1})
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 10, 2: 20}, values)
+	hits, shadows := cache.HitStats()
+	require.Equal(t, HitStats{Misses: 3}, hits)
+	require.Equal(t, HitStats{Misses: 3}, shadows["big"])
+	require.Equal(t, 2, shadow.Size())
+
+	_, err = cache.LoadMulti(context.Background(), []int{1})
+	require.NoError(t, err)
+	hits, shadows = cache.HitStats()
+	require.Equal(t, HitStats{Hits: 1, Misses: 3}, hits)
+	require.Equal(t, HitStats{Hits: 1, Misses: 3}, shadows["big"])
+
+	cache.PutTransient(3, 30)
+	freq, err := shadow.GetKeyFrequency(3)
+	require.NoError(t, err)
+	require.Equal(t, 0, freq)
+
+	source, err := NewWithOptions(4, WithChangeHistory[int, int](2))
+	require.NoError(t, err)
+	for i := 5; i <= 8; i++ {
+		source.Put(i, i)
+	}
+	cache.Clear()
+	copied, err := cache.WarmFrom(source, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, copied)
+	require.Equal(t, 4, shadow.Size())
+
+	gen := source.Generation()
+	require.NoError(t, source.Remove(5))
+	source.Put(9, 9)
+	for range 3 {
+		_, _ = source.Get(9)
+	}
+	_, err = cache.SyncFrom(source, gen)
+	require.NoError(t, err)
+	_, err = shadow.EntryInfo(5)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	freq, err = shadow.GetKeyFrequency(9)
+	require.NoError(t, err)
+	require.Equal(t, 4, freq)
+
+	source.Put(10, 10)
+	source.Put(11, 11)
+	source.Put(12, 12)
+	_, err = cache.SyncFrom(source, gen)
+	require.NoError(t, err)
+	keys, _ := collect(shadow.All())
+	primaryKeys, _ := collect(primary.All())
+	require.Equal(t, primaryKeys, keys)
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write([]byte) (int, error) {
+	w.writes++
+	return 0, errors.New("write failed")
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithRecorder[string, int](nil, false))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var trace strings.Builder
+	cache, err := NewWithOptions(2, WithRecorder[string, int](&trace, false))
+	require.NoError(t, err)
+	cache.Put("a", 1)
+	_, _ = cache.Get("a")
+	_, _ = cache.Get("b")
+	_ = cache.Remove("a")
+	require.NoError(t, cache.Apply([]Op[string, int]{{Kind: OpPut, Key: "c"}}))
+	require.Equal(t, "P a\nG a\nG b\nR a\nP c\n", trace.String())
+
+	trace.Reset()
+	cache, err = NewWithOptions(2, WithRecorder[string, int](&trace, true))
+	require.NoError(t, err)
+	cache.Put("a", 1)
+	_, _ = cache.Get("a")
+	lines := strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	require.Regexp(t, "^P [0-9a-f]{16}$", lines[0])
+	require.Equal(t, lines[0][2:], strings.TrimPrefix(lines[1], "G "))
+
+	writer := &failingWriter{}
+	cache, err = NewWithOptions(2, WithRecorder[string, int](writer, false))
+	require.NoError(t, err)
+	cache.Put("a", 1)
+	cache.Put("b", 1)
+	require.Equal(t, 1, writer.writes)
+	require.Equal(t, 2, cache.Size())
+}
+
+func TestPage(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](5)
+	for i := 1; i <= 5; i++ {
+		cache.Put(i, i*10)
+	}
+
+	var keys []int
+	pages := 0
+	for cursor := (Cursor{}); !cursor.Done(); pages++ {
+		var entries []Entry[int, int]
+		entries, cursor = cache.Page(cursor, 2)
+		require.LessOrEqual(t, len(entries), 2)
+		for _, entry := range entries {
+			require.Equal(t, entry.Key*10, entry.Value)
+			keys = append(keys, entry.Key)
+		}
+	}
+	require.Equal(t, 3, pages)
+	allKeys, _ := collect(cache.All())
+	require.Equal(t, allKeys, keys)
+
+	entries, cursor := cache.Page(Cursor{}, 5)
+	require.Len(t, entries, 5)
+	require.True(t, cursor.Done())
+	entries, next := cache.Page(cursor, 5)
+	require.Empty(t, entries)
+	require.Equal(t, cursor, next)
+
+	entries, cursor = cache.Page(Cursor{}, 0)
+	require.Empty(t, entries)
+	require.False(t, cursor.Done())
+
+	first, cursor := cache.Page(Cursor{}, 2)
+	require.NoError(t, cache.Remove(first[0].Key))
+	second, _ := cache.Page(cursor, 2)
+	require.Equal(t, allKeys[3], second[0].Key)
+}
+
+func TestUtilization(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](3)
+	require.Equal(t, 3, cache.Available())
+	cache.Put(1, 1)
+	require.Equal(t, 2, cache.Available())
+	require.Equal(t, int64(0), cache.Weight())
+	require.Equal(t, int64(0), cache.MaxWeight())
+
+	weighted, err := NewWithOptions(3, WithMaxWeight[int, int](10, func(_, value int) int64 {
+		return int64(value)
+	}))
+	require.NoError(t, err)
+	weighted.Put(1, 4)
+	weighted.Put(2, 5)
+	weighted.Put(1, 3)
+	require.Equal(t, int64(8), weighted.Weight())
+	require.Equal(t, int64(10), weighted.MaxWeight())
+	require.Equal(t, 1, weighted.Available())
+	require.NoError(t, weighted.Remove(2))
+	require.Equal(t, int64(3), weighted.Weight())
+	weighted.Clear()
+	require.Equal(t, int64(0), weighted.Weight())
+}
+
+func TestDebugDump(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithRedactor[string, string](nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var dump strings.Builder
+	cache := New[string, string](2)
+	cache.Put("user", "alice")
+	cache.Put("token", "secret")
+	_, _ = cache.Get("token")
+	require.NoError(t, cache.DebugDump(&dump))
+	require.Equal(t, "token\t2\tsecret\nuser\t1\talice\n", dump.String())
+
+	cache, err = NewWithOptions(2, WithRedactor[string, string](func(key, value string) string {
+		if key == "token" {
+			return "[redacted]"
+		}
+		return value
+	}))
+	require.NoError(t, err)
+	cache.Put("user", "alice")
+	cache.Put("token", "secret")
+	dump.Reset()
+	require.NoError(t, cache.DebugDump(&dump))
+	require.Equal(t, "token\t1\t[redacted]\nuser\t1\talice\n", dump.String())
+
+	require.Error(t, cache.DebugDump(&failingWriter{}))
+}
+
+func TestExportDOT(t *testing.T) {
+	t.Parallel()
+
+	var dot strings.Builder
+	cache := New[string, int](3)
+	require.NoError(t, cache.ExportDOT(&dot))
+	require.Equal(t, "digraph lfu {\n\trankdir=LR;\n\tnode [shape=box];\n\thead [shape=point];\n\thead -> head;\n}\n", dot.String())
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	_, _ = cache.Get("a")
+	dot.Reset()
+	require.NoError(t, cache.ExportDOT(&dot))
+	require.Equal(t, `digraph lfu {
+	rankdir=LR;
+	node [shape=box];
+	head [shape=point];
+	subgraph cluster_2 {
+		label="frequency 2, 1 elements";
+		e0 [label="a\n1"];
+		start2 [shape=plaintext, label="start of 2"];
+		start2 -> e0 [style=dashed];
+	}
+	subgraph cluster_1 {
+		label="frequency 1, 1 elements";
+		e1 [label="b\n2"];
+		start1 [shape=plaintext, label="start of 1"];
+		start1 -> e1 [style=dashed];
+	}
+	head -> e0;
+	e0 -> e1;
+	e1 -> head;
+}
+`, dot.String())
+
+	require.Error(t, cache.ExportDOT(&failingWriter{}))
+}
+
+func TestStatsReporter(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(2, WithStatsReporter[int, int](0, func(Stats) {}))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(2, WithStatsReporter[int, int](time.Second, nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var reports []Stats
+	cache, err := NewWithOptions(2,
+		WithMemoryPressure[int, int](math.MaxUint64),
+		WithStatsReporter[int, int](time.Millisecond, func(stats Stats) {
+			reports = append(reports, stats)
+		}))
+	require.NoError(t, err)
+	cache.Put(1, 1)
+
+	require.Eventually(t, func() bool {
+		_, _ = cache.Get(1)
+		return len(reports) > 0
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 1, reports[0].Blocks)
+
+	require.NoError(t, cache.Close())
+	reported := len(reports)
+	time.Sleep(10 * time.Millisecond)
+	_, _ = cache.Get(1)
+	require.Len(t, reports, reported)
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	type user struct {
+		name   string
+		region string
+	}
+	_, err := NewWithOptions(3, WithIndex[int, user, string]("region", nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(3, WithIndex[int, user]("region", func(u user) string {
+		return u.region
+	}))
+	require.NoError(t, err)
+	lookup := func(region any) []int {
+		return slices.Sorted(cache.LookupIndex("region", region))
+	}
+
+	cache.Put(1, user{"alice", "eu"})
+	cache.Put(2, user{"bob", "us"})
+	cache.Put(3, user{"carol", "eu"})
+	require.Equal(t, []int{1, 3}, lookup("eu"))
+	require.Equal(t, []int{2}, lookup("us"))
+
+	cache.Put(3, user{"carol", "us"})
+	require.Equal(t, []int{1}, lookup("eu"))
+	require.Equal(t, []int{2, 3}, lookup("us"))
+
+	cache.Put(4, user{"dave", "eu"})
+	require.Equal(t, []int{4}, lookup("eu"))
+	require.NoError(t, cache.Remove(2))
+	require.Equal(t, []int{3}, lookup("us"))
+
+	require.Empty(t, lookup(1))
+	require.Empty(t, slices.Collect(cache.LookupIndex("name", "eu")))
+
+	cache.Clear()
+	require.Empty(t, lookup("eu"))
+}
+
+func TestInterning(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(3, WithInterning[int, []byte](nil, bytes.Equal))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(3, WithComparableInterning[int, string]())
+	require.NoError(t, err)
+	cache.Put(1, strings.Repeat("a", 64))
+	cache.Put(2, strings.Repeat("a", 64))
+	cache.Put(3, strings.Repeat("b", 64))
+	first, _ := cache.Get(1)
+	second, _ := cache.Get(2)
+	require.Equal(t, unsafe.StringData(first), unsafe.StringData(second))
+	require.Equal(t, 2, cache.Stats().InternedValues)
+
+	cache.Put(3, strings.Repeat("a", 64))
+	require.Equal(t, 1, cache.Stats().InternedValues)
+	require.NoError(t, cache.Remove(1))
+	require.NoError(t, cache.Remove(2))
+	require.Equal(t, 1, cache.Stats().InternedValues)
+	cache.Put(4, "c")
+	_, _ = cache.Get(4)
+	cache.Put(5, "d")
+	cache.Put(6, "d")
+	require.Equal(t, 3, cache.Stats().InternedValues)
+	cache.Clear()
+	require.Equal(t, 0, cache.Stats().InternedValues)
+
+	seed := maphash.MakeSeed()
+	blobs, err := NewWithOptions(2, WithInterning[int](func(value []byte) uint64 {
+		return maphash.Bytes(seed, value)
+	}, bytes.Equal))
+	require.NoError(t, err)
+	blobs.Put(1, []byte("blob"))
+	blobs.Put(2, []byte("blob"))
+	a, _ := blobs.Get(1)
+	b, _ := blobs.Get(2)
+	require.Same(t, &a[0], &b[0])
+}
+
+func TestMaxValueWeight(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(5, WithMaxValueWeight[string, int](0))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	_, err = NewWithOptions(5, WithMaxValueWeight[string, int](10))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	cache, err := NewWithOptions(5,
+		WithMaxWeight[string, int](100, func(_ string, value int) int64 {
+			return int64(value)
+		}),
+		WithMaxValueWeight[string, int](10))
+	require.NoError(t, err)
+
+	require.NoError(t, cache.TryPut("a", 5))
+	require.NoError(t, cache.TryPut("b", 5))
+	require.ErrorIs(t, cache.TryPut("big", 50), ErrValueTooHeavy)
+	require.Equal(t, 2, cache.Size())
+
+	cache.Put("a", 11)
+	_, err = cache.Get("a")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.ErrorIs(t, cache.TryPut("huge", 101), ErrValueTooHeavy)
+
+	stats := cache.Stats()
+	require.Equal(t, uint64(3), stats.Rejections)
+	require.Equal(t, int64(5), cache.Weight())
+
+	require.NoError(t, New[string, int](1).TryPut("a", 1000))
+}
+
+func TestSynchronized(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSynchronized[int, int](nil)
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	var cache *synchronizedImpl[int, int]
+	loads := 0
+	loader := BulkLoaderFunc[int, int](func(_ context.Context, keys []int) (map[int]int, error) {
+		loads++
+		if keys[0] == 2000 {
+			// the loader is called without the lock, so the cache can be used meanwhile
+			cache.Put(2000, 7)
+		}
+		return map[int]int{keys[0]: keys[0] * 10}, nil
+	})
+	inner, err := NewWithOptions(100, WithBulkLoader[int, int](loader))
+	require.NoError(t, err)
+	cache, err = NewSynchronized[int, int](inner)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				cache.Put(i*100+j, j)
+				_, _ = cache.Get(i*100 + j)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, uint64(1600), cache.WaitStats().Acquisitions)
+
+	value, err := cache.GetCtx(context.Background(), 1000)
+	require.NoError(t, err)
+	require.Equal(t, 10000, value)
+	require.NoError(t, cache.Remove(1000))
+	value, err = cache.GetCtx(context.Background(), 2000)
+	require.NoError(t, err)
+	require.Equal(t, 7, value)
+	require.Equal(t, 2, loads)
+
+	require.NoError(t, cache.Apply([]Op[int, int]{{Kind: OpRemove, Key: 2000}}))
+	cache.PutMulti(map[int]int{2000: 2})
+	require.Equal(t, map[int]int{2000: 2}, cache.GetMulti([]int{2000, 3000}))
+
+	plain, err := NewSynchronized[int, int](New[int, int](2))
+	require.NoError(t, err)
+	_, err = plain.GetCtx(context.Background(), 1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	plain.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, plain.PutCtx(ctx, 1, 1), context.DeadlineExceeded)
+	_, err = plain.GetCtx(ctx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	plain.Unlock()
+
+	stats := plain.WaitStats()
+	require.Equal(t, uint64(2), stats.Abandoned)
+	require.GreaterOrEqual(t, stats.Waited, time.Millisecond)
+	require.NoError(t, plain.PutCtx(context.Background(), 1, 1))
+
+	var wrapped Cache[int, int] = plain
+	wrapped.PutTransient(2, 2)
+	_, _, evicted := wrapped.PutWithEvicted(3, 3)
+	require.True(t, evicted)
+	for key := range wrapped.All() {
+		// the loop body can use the cache
+		freq, err := wrapped.GetKeyFrequency(key)
+		require.NoError(t, err)
+		require.Equal(t, 1, freq)
+	}
+	require.Equal(t, 2, wrapped.Size())
+	require.Equal(t, 2, wrapped.Capacity())
+	require.Equal(t, 1, wrapped.Stats().Blocks)
+	wrapped.Clear()
+	require.Zero(t, wrapped.Size())
+}
+
+func TestSyncFrom(t *testing.T) {
+	t.Parallel()
+
+	primary, err := NewWithOptions(5, WithChangeHistory[int, int](4))
+	require.NoError(t, err)
+	follower := New[int, int](5)
+
+	primary.Put(1, 10)
+	primary.Put(2, 20)
+	_, _ = primary.Get(1)
+	gen, err := follower.SyncFrom(primary, 0)
+	require.NoError(t, err)
+	require.Equal(t, primary.Generation(), gen)
+
+	primary.Put(3, 30)
+	primary.Put(2, 21)
+	require.NoError(t, primary.Remove(1))
+	gen, err = follower.SyncFrom(primary, gen)
+	require.NoError(t, err)
+	keys, values := collect(follower.All())
+	require.Equal(t, []int{2, 3}, keys)
+	require.Equal(t, []int{21, 30}, values)
+	freq, err := follower.GetKeyFrequency(2)
+	require.NoError(t, err)
+	require.Equal(t, 2, freq)
+
+	for i := 4; i <= 9; i++ {
+		primary.Put(i, i*10)
+	}
+	follower.Put(100, 100)
+	gen, err = follower.SyncFrom(primary, gen)
+	require.NoError(t, err)
+	require.Equal(t, primary.Generation(), gen)
+	primaryKeys, _ := collect(primary.All())
+	keys, _ = collect(follower.All())
+	require.ElementsMatch(t, primaryKeys, keys)
+
+	_, err = follower.SyncFrom(nil, gen)
+	require.ErrorIs(t, err, ErrNilSource)
+
+	changesOnly := struct{ SyncSource[int, int] }{primary}
+	primary.Put(10, 100)
+	gen, err = follower.SyncFrom(changesOnly, gen)
+	require.NoError(t, err)
+	value, err := follower.Get(10)
+	require.NoError(t, err)
+	require.Equal(t, 100, value)
+
+	for i := 11; i <= 15; i++ {
+		primary.Put(i, i*10)
+	}
+	since, err := follower.SyncFrom(changesOnly, gen)
+	require.ErrorIs(t, err, ErrHistoryTruncated)
+	require.Equal(t, gen, since)
+	require.Equal(t, 5, follower.Size())
+
+	restarted, err := NewWithOptions(5, WithChangeHistory[int, int](4))
+	require.NoError(t, err)
+	restarted.Put(7, 70)
+	gen, err = follower.SyncFrom(restarted, gen)
+	require.NoError(t, err)
+	require.Equal(t, restarted.Generation(), gen)
+	keys, values = collect(follower.All())
+	require.Equal(t, []int{7}, keys)
+	require.Equal(t, []int{70}, values)
+}
+
+func TestReplication(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewWithOptions(3, WithReplication[int, int](nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+
+	replica := New[int, int](3)
+	ops := make(chan Op[int, int], 16)
+	primary, err := NewWithOptions(2, WithReplication(func(op Op[int, int]) {
+		ops <- op
+	}))
+	require.NoError(t, err)
+
+	primary.Put(1, 10)
+	primary.Put(2, 20)
+	primary.Put(1, 11)
+	primary.Put(3, 30)
+	require.NoError(t, primary.Apply([]Op[int, int]{{Kind: OpRemove, Key: 1}}))
+	require.ErrorIs(t, primary.Remove(1), ErrKeyNotFound)
+	close(ops)
+
+	for op := range ops {
+		require.NoError(t, replica.Apply([]Op[int, int]{op}))
+	}
+	keys, values := collect(replica.All())
+	require.Equal(t, []int{3, 2}, keys)
+	require.Equal(t, []int{30, 20}, values)
+
+	var streamed []Op[int, int]
+	primary, err = NewWithOptions(3, WithReplication(func(op Op[int, int]) {
+		streamed = append(streamed, op)
+	}))
+	require.NoError(t, err)
+	source := New[int, int](3)
+	source.Put(1, 10)
+	source.Put(2, 20)
+	_, err = primary.WarmFrom(source, 0)
+	require.NoError(t, err)
+	primary.PutTransient(3, 30)
+	primary.Clear()
+	require.Equal(t, []Op[int, int]{
+		{Kind: OpPut, Key: 1, Value: 10},
+		{Kind: OpPut, Key: 2, Value: 20},
+		{Kind: OpPut, Key: 3, Value: 30},
+		{Kind: OpClear},
+	}, streamed)
+
+	require.NoError(t, replica.Apply(streamed[:3]))
+	require.Equal(t, 3, replica.Size())
+	require.NoError(t, replica.Apply(streamed[3:]))
+	require.Equal(t, 0, replica.Size())
+}
+
+func TestPutTransient(t *testing.T) {
+	t.Parallel()
+
+	cache := New[int, int](3)
+	cache.PutTransient(1, 10)
+	cache.Put(2, 20)
+	cache.Put(3, 30)
+	require.NoError(t, cache.CheckInvariants())
+	freq, err := cache.GetKeyFrequency(1)
+	require.NoError(t, err)
+	require.Equal(t, 0, freq)
+
+	victim, _, evicted := cache.PutWithEvicted(4, 40)
+	require.True(t, evicted)
+	require.Equal(t, 1, victim)
+
+	cache.PutTransient(5, 50)
+	_, err = cache.Get(5)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.Equal(t, 3, cache.Size())
+
+	require.NoError(t, cache.Remove(4))
+	cache.PutTransient(5, 50)
+	cache.PutTransient(6, 60)
+	require.NoError(t, cache.CheckInvariants())
+	keys, _ := collect(cache.EvictionOrder())
+	require.Equal(t, []int{6, 2, 3}, keys)
+
+	_, err = cache.Get(5)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	value, err := cache.Get(6)
+	require.NoError(t, err)
+	require.Equal(t, 60, value)
+	require.NoError(t, cache.CheckInvariants())
+	keys, _ = collect(cache.EvictionOrder())
+	require.Equal(t, []int{2, 3, 6}, keys)
+
+	for _, opt := range []Option[int, int]{
+		WithLogFrequency[int, int](),
+		WithMorrisCounter[int, int](1, rand.NewPCG(1, 1)),
+		WithSampleRate[int, int](2),
+	} {
+		cache, err := NewWithOptions(3, opt)
+		require.NoError(t, err)
+		cache.Put(1, 1)
+		cache.PutTransient(2, 2)
+		for range 4 {
+			_, _ = cache.Get(2)
+		}
+		cache.Put(3, 3)
+		require.NoError(t, cache.CheckInvariants())
+	}
+}
+
+func TestPutTransientWithMaxWeight(t *testing.T) {
+	t.Parallel()
+
+	weigher := func(_, value int) int64 { return int64(value) }
+	cache, err := NewWithOptions(5, WithMaxWeight[int, int](10, weigher))
+	require.NoError(t, err)
+	cache.Put(1, 5)
+	cache.Put(2, 5)
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(2)
+
+	cache.PutTransient(3, 6)
+	require.NoError(t, cache.CheckInvariants())
+	keys, _ := collect(cache.EvictionOrder())
+	require.Equal(t, []int{1, 2}, keys)
+
+	require.NoError(t, cache.Remove(2))
+	cache.PutTransient(3, 3)
+	cache.PutTransient(4, 2)
+	cache.PutTransient(5, 6)
+	require.NoError(t, cache.CheckInvariants())
+	keys, _ = collect(cache.EvictionOrder())
+	require.Equal(t, []int{3, 4, 1}, keys)
+
+	cache.PutTransient(5, 5)
+	keys, _ = collect(cache.EvictionOrder())
+	require.Equal(t, []int{5, 1}, keys)
+
+	src := New[int, int](1)
+	src.Put(6, 6)
+	n, err := cache.WarmFrom(src, 0)
+	require.NoError(t, err)
+	require.Zero(t, n)
+	keys, _ = collect(cache.EvictionOrder())
+	require.Equal(t, []int{5, 1}, keys)
+
+	_, _ = src.Get(6)
+	_, _ = src.Get(6)
+	n, err = cache.WarmFrom(src, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	keys, _ = collect(cache.EvictionOrder())
+	require.Equal(t, []int{6}, keys)
+	require.NoError(t, cache.CheckInvariants())
+}
+
+func TestPanicIsolation(t *testing.T) {
+	t.Parallel()
+
+	var panics []error
+	var evicted []int
+	loader := BulkLoaderFunc[int, int](func(context.Context, []int) (map[int]int, error) {
+		panic("backend is down")
+	})
+	cache, err := NewWithOptions(2,
+		WithOnEvict(func(entry Entry[int, int], _ Reason) {
+			evicted = append(evicted, entry.Key)
+			panic("buggy callback")
+		}),
+		WithReplication(func(Op[int, int]) { panic("buggy sink") }),
+		WithBulkLoader[int, int](loader),
+		WithPanicHandler[int, int](func(err error) { panics = append(panics, err) }),
+	)
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	cache.PutWithCallback(3, 30, func(Entry[int, int], Reason) { panic("buggy entry callback") })
+	require.Equal(t, []int{1}, evicted)
+	require.Equal(t, 2, cache.Size())
+	require.NoError(t, cache.CheckInvariants())
+	require.Len(t, panics, 4)
+	for _, err := range panics {
+		require.ErrorIs(t, err, ErrCallbackPanic)
+	}
+
+	require.NoError(t, cache.Remove(3))
+	require.Equal(t, []int{1, 3}, evicted)
+	require.Len(t, panics, 7)
+
+	values, err := cache.LoadMulti(context.Background(), []int{2, 4})
+	require.ErrorIs(t, err, ErrCallbackPanic)
+	require.Equal(t, map[int]int{2: 20}, values)
+	require.Len(t, panics, 8)
+	require.Equal(t, uint64(8), cache.Stats().CallbackPanics)
+
+	cache.Prefetch(context.Background(), []int{5})
+	require.Eventually(t, func() bool {
+		_, _ = cache.Get(2)
+		return cache.Stats().CallbackPanics == 9
+	}, time.Second, time.Millisecond)
+	require.NoError(t, cache.CheckInvariants())
+
+	_, err = NewWithOptions(1, WithPanicHandler[int, int](nil))
+	require.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestPanicIsolationOfUserFunctions(t *testing.T) {
+	t.Parallel()
+
+	var panics []error
+	cache, err := NewWithOptions(4,
+		WithMaxWeight(100, func(_ int, value int) int64 {
+			if value < 0 {
+				panic("buggy weigher")
+			}
+			return 1
+		}),
+		WithIndex[int, int]("parity", func(value int) int {
+			if value == 13 {
+				panic("buggy index")
+			}
+			return value % 2
+		}),
+		WithNamespaceQuotas[int, int](func(key int) string {
+			if key == 7 {
+				panic("buggy namespace")
+			}
+			return "odd"
+		}, map[string]int{"odd": 3}),
+		WithInterning[int, int](func(value int) uint64 {
+			if value == 99 {
+				panic("buggy hash")
+			}
+			return uint64(value)
+		}, func(a, b int) bool { return a == b }),
+		WithRedactor(func(key, _ int) string {
+			if key == 1 {
+				panic("buggy redactor")
+			}
+			return "***"
+		}),
+		WithPanicHandler[int, int](func(err error) { panics = append(panics, err) }),
+	)
+	require.NoError(t, err)
+
+	cache.Put(1, 10)
+	require.ErrorIs(t, cache.TryPut(1, -1), ErrCallbackPanic)
+	_, err = cache.Get(1)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	require.ErrorIs(t, cache.Apply([]Op[int, int]{{Kind: OpPut, Key: 2, Value: -1}}), ErrCallbackPanic)
+	require.Equal(t, 0, cache.Size())
+
+	cache.Put(1, 13)
+	cache.Put(3, 99)
+	cache.Put(7, 70)
+	require.Equal(t, 3, cache.Size())
+	require.Equal(t, []int{7}, slices.Collect(cache.LookupIndex("parity", 0)))
+	require.Equal(t, []int{3}, slices.Collect(cache.LookupIndex("parity", 1)))
+	require.NoError(t, cache.CheckInvariants())
+
+	var dump strings.Builder
+	require.NoError(t, cache.DebugDump(&dump))
+	require.Contains(t, dump.String(), "[redactor panicked]")
+	require.NotContains(t, dump.String(), "13")
+
+	require.NoError(t, cache.Remove(1))
+	require.NoError(t, cache.Remove(3))
+	require.NoError(t, cache.Remove(7))
+	require.Equal(t, 0, cache.Size())
+	require.Empty(t, slices.Collect(cache.LookupIndex("parity", 0)))
+	require.NoError(t, cache.CheckInvariants())
+	for _, err := range panics {
+		require.ErrorIs(t, err, ErrCallbackPanic)
+	}
+	require.Equal(t, uint64(len(panics)), cache.Stats().CallbackPanics)
+	require.GreaterOrEqual(t, len(panics), 7)
+}
+
+func TestWithoutStats(t *testing.T) {
+	t.Parallel()
+
+	cache, err := NewWithOptions(2, WithMaxFrequency[int, int](1), WithoutStats[int, int]())
+	require.NoError(t, err)
+	cache.Put(1, 10)
+	cache.Put(2, 20)
+	_, _ = cache.Get(1)
+	_, _ = cache.Get(1)
+	stats := cache.Stats()
+	require.Zero(t, stats.Saturations)
+	require.Equal(t, 1, stats.Blocks)
+	require.Equal(t, 2, stats.LowestBlockSize)
+
+	counted, err := NewWithOptions(2, WithMaxFrequency[int, int](1))
+	require.NoError(t, err)
+	counted.Put(1, 10)
+	_, _ = counted.Get(1)
+	require.Equal(t, uint64(1), counted.Stats().Saturations)
+}
+
+func benchmarkOps(b *testing.B, opts ...Option[int, int]) {
+	b.Helper()
+	cache, err := NewWithOptions(1024, opts...)
+	require.NoError(b, err)
+	keys := make([]int, 4096)
+	r := rand.New(rand.NewPCG(1, 1))
+	for i := range keys {
+		keys[i] = r.IntN(2048)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := range b.N {
+		key := keys[i%len(keys)]
+		if _, err := cache.Get(key); err != nil {
+			cache.Put(key, i)
+		}
+	}
+}
+
+func BenchmarkDefaultPath(b *testing.B) {
+	b.Run("cache", defaultPathWorkload(func(capacity int) *cacheImpl[int, int] { return New[int, int](capacity) }))
+	b.Run("baseline", defaultPathWorkload(newBaselineLFU))
+}
+
+func BenchmarkOps(b *testing.B) {
+	b.Run("default", func(b *testing.B) { benchmarkOps(b) })
+	b.Run("without stats", func(b *testing.B) { benchmarkOps(b, WithoutStats[int, int]()) })
+	b.Run("recorder", func(b *testing.B) { benchmarkOps(b, WithRecorder[int, int](io.Discard, true)) })
+	b.Run("stats reporter", func(b *testing.B) {
+		benchmarkOps(b, WithStatsReporter[int, int](time.Hour, func(Stats) {}))
+	})
+}
+
 func collect[K comparable, V any](iterator iter.Seq2[K, V]) ([]K, []V) {
 	keys := make([]K, 0)
 	values := make([]V, 0)
@@ -0,0 +1,133 @@
+package lfu
+
+import (
+	"context"
+	"errors"
+	"maps"
+)
+
+var ErrNoLoader = errors.New("loader is not configured")
+
+// MultiLoader is implemented by caches loading the keys missing in them, like the caches of New and NewWithOptions,
+// which load them with the BulkLoader set by WithBulkLoader or return ErrNoLoader without it
+type MultiLoader[K comparable, V any] interface {
+	LoadMulti(ctx context.Context, keys []K) (map[K]V, error)
+}
+
+// BulkLoader loads values of many keys with a single backend call.
+// Keys missing in the backend are omitted from the result
+type BulkLoader[K comparable, V any] interface {
+	LoadMany(ctx context.Context, keys []K) (map[K]V, error)
+}
+
+// BulkLoaderFunc is an adapter to use an ordinary function as BulkLoader
+type BulkLoaderFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// LoadMany calls f(ctx, keys)
+func (f BulkLoaderFunc[K, V]) LoadMany(ctx context.Context, keys []K) (map[K]V, error) {
+	return f(ctx, keys)
+}
+
+// WithBulkLoader sets the loader used by LoadMulti. Wrap it with NewCoalescingLoader to merge
+// concurrent misses of caches shared by many goroutines. Returns ErrInvalidOption if loader is nil.
+func WithBulkLoader[K comparable, V any](loader BulkLoader[K, V]) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if loader == nil {
+			return ErrInvalidOption
+		}
+		c.extend().loader = loader
+		return nil
+	}
+}
+
+// load calls the loader and turns its panic into an error
+func load[K comparable, V any](ctx context.Context, loader BulkLoader[K, V], keys []K) (values map[K]V, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			values, err = nil, panicError("loader", recovered)
+		}
+	}()
+	return loader.LoadMany(ctx, keys)
+}
+
+// LoadMulti works like GetMulti, but loads all keys missing in the cache with a single call
+// of the configured BulkLoader and puts the loaded values into the cache.
+// Returns ErrNoLoader if the cache has no loader, or the loader error or ErrCircuitOpen
+// together with the cached values.
+//
+// O(len(keys)) plus the loader call
+func (l *cacheImpl[K, V]) LoadMulti(ctx context.Context, keys []K) (map[K]V, error) {
+	if l.ext == nil || l.ext.loader == nil {
+		return nil, ErrNoLoader
+	}
+	values := make(map[K]V, len(keys))
+	pending := make(map[K]struct{})
+	var missing []K
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if _, ok := pending[key]; ok {
+			continue
+		}
+		if value, err := l.Get(key); err == nil {
+			values[key] = value
+		} else {
+			pending[key] = struct{}{}
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	loader, err := l.startLoad()
+	if err != nil {
+		return values, err
+	}
+	loaded, err := load(ctx, loader, missing)
+	loaded, err = l.finishLoad(missing, loaded, err)
+	if err != nil {
+		return values, err
+	}
+	maps.Copy(values, loaded)
+	return values, nil
+}
+
+// startLoad returns the loader if the keys missing in the cache can be loaded now,
+// otherwise, returns ErrNoLoader or ErrCircuitOpen. The loader may be called without
+// holding the lock guarding the cache, with finishLoad called afterward under the lock
+func (l *cacheImpl[K, V]) startLoad() (BulkLoader[K, V], error) {
+	if l.ext == nil || l.ext.loader == nil {
+		return nil, ErrNoLoader
+	}
+	if l.ext.breaker != nil && !l.ext.breaker.allow(l.clock()) {
+		return nil, ErrCircuitOpen
+	}
+	return l.ext.loader, nil
+}
+
+// finishLoad records the result of the loader call started by startLoad and puts the loaded values
+// into the cache. Keys put into the cache while they were being loaded keep their values,
+// which are returned instead of the loaded ones
+func (l *cacheImpl[K, V]) finishLoad(keys []K, loaded map[K]V, err error) (map[K]V, error) {
+	if errors.Is(err, ErrCallbackPanic) {
+		l.reportPanic(err)
+	}
+	if l.ext.breaker != nil {
+		l.ext.breaker.record(err, l.clock())
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[K]V, len(loaded))
+	for _, key := range keys {
+		if link, ok := l.keyToElement[key]; ok {
+			values[key] = link.Value.value
+		} else if value, ok := loaded[key]; ok {
+			l.put(key, value)
+			values[key] = value
+		}
+	}
+	return values, nil
+}
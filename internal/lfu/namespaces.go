@@ -0,0 +1,179 @@
+package lfu
+
+import (
+	"lfucache/internal/linkedlist"
+	"maps"
+)
+
+// namespaces tracks the number of entries and the total weight of every namespace sharing the cache
+type namespaces[K comparable] struct {
+	namespaceOf func(K) string
+	report      func(error)
+	quotas      map[string]int
+	sizes       map[string]int
+	maxWeights  map[string]int64
+	weights     map[string]int64
+}
+
+// WithNamespaceQuotas lets namespaces share the cache without evicting each other's entries:
+// namespaceOf returns the namespace of a key, and a namespace having as many entries as its quota
+// makes room for a new key by evicting its own least frequently used entry instead of the cache-wide one.
+// Namespaces without a quota are limited only by the capacity. Quotas limit the number of entries,
+// WithNamespaceMaxWeights limits their weight. Inserting a key of a namespace at its quota becomes
+// O(capacity) in the worst case.
+// Returns ErrInvalidOption if namespaceOf is nil or a quota is not positive.
+func WithNamespaceQuotas[K comparable, V any](namespaceOf func(K) string, quotas map[string]int) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if namespaceOf == nil {
+			return ErrInvalidOption
+		}
+		for _, quota := range quotas {
+			if quota < 1 {
+				return ErrInvalidOption
+			}
+		}
+		c.extend().namespaces = &namespaces[K]{
+			namespaceOf: namespaceOf,
+			report:      c.reportPanic,
+			quotas:      maps.Clone(quotas),
+			sizes:       make(map[string]int, len(quotas)),
+			weights:     make(map[string]int64, len(quotas)),
+		}
+		return nil
+	}
+}
+
+// WithNamespaceMaxWeights limits the total weight of the entries of namespaces of WithNamespaceQuotas
+// in addition to their number: a namespace makes room for a new entry by evicting its own least frequently
+// used entries until the entry fits in its max weight, and values heavier than it are rejected like
+// by WithMaxValueWeight. Max weights cannot be borrowed. Namespaces without a max weight are limited only
+// by the max weight of the cache. It needs WithNamespaceQuotas and WithMaxWeight in any order.
+// Returns ErrInvalidOption if a max weight is not positive or, from NewWithOptions, if an option it needs is missing.
+func WithNamespaceMaxWeights[K comparable, V any](maxWeights map[string]int64) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		for _, maxWeight := range maxWeights {
+			if maxWeight < 1 {
+				return ErrInvalidOption
+			}
+		}
+		c.extend().namespaceMaxWeights = maps.Clone(maxWeights)
+		return nil
+	}
+}
+
+// of returns the namespace of the key, or "" if the namespace function panics
+func (n *namespaces[K]) of(key K) string {
+	ns, _ := guarded(n.report, "namespace function", func() string { return n.namespaceOf(key) })
+	return ns
+}
+
+func (n *namespaces[K]) add(key K, delta int, weight int64) {
+	ns := n.of(key)
+	if n.sizes[ns] += delta; n.sizes[ns] == 0 {
+		delete(n.sizes, ns)
+	}
+	if n.weights[ns] += weight; n.weights[ns] == 0 {
+		delete(n.weights, ns)
+	}
+}
+
+// tooHeavy reports whether a value of the key of the given weight exceeds the max weight of its namespace
+func (n *namespaces[K]) tooHeavy(key K, weight int64) bool {
+	maxWeight, ok := n.maxWeights[n.of(key)]
+	return ok && weight > maxWeight
+}
+
+// WithQuotaBorrowing lets namespaces of WithNamespaceQuotas exceed their quotas by borrowing
+// the unused quota of the other namespaces. Borrowed entries are reclaimed lazily: a namespace
+// under its quota makes room for a new key by evicting the least frequently used borrowed entry
+// when the lent quota is needed. The number of borrowed entries and reclaims are reported in Stats.
+// Inserting a key becomes O(number of namespaces) and O(capacity) in the worst case.
+func WithQuotaBorrowing[K comparable, V any]() Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		c.extend().quotaBorrowing = true
+		return nil
+	}
+}
+
+// usage returns the number of entries of namespaces over their quotas and the unused quota of the others
+func (n *namespaces[K]) usage() (borrowed, unused int) {
+	for ns, quota := range n.quotas {
+		if size := n.sizes[ns]; size > quota {
+			borrowed += size - quota
+		} else {
+			unused += quota - size
+		}
+	}
+	return borrowed, unused
+}
+
+func (n *namespaces[K]) overQuota(key K) bool {
+	ns := n.of(key)
+	quota, ok := n.quotas[ns]
+	return ok && n.sizes[ns] > quota
+}
+
+// namespaceVictim returns the element to evict before inserting the key to keep namespaces within
+// their quotas, otherwise, returns nil. It is the least frequently used element of the namespace
+// of the key if the namespace has reached its quota and cannot borrow, or the least frequently used
+// borrowed element if the key needs the lent quota
+func (l *cacheImpl[K, V]) namespaceVictim(key K) *linkedlist.Node[*element[K, V]] {
+	if l.ext == nil || l.ext.namespaces == nil {
+		return nil
+	}
+	ns := l.ext.namespaces.of(key)
+	quota, ok := l.ext.namespaces.quotas[ns]
+	if !ok {
+		return nil
+	}
+	var borrowed, unused int
+	if l.ext.quotaBorrowing {
+		borrowed, unused = l.ext.namespaces.usage()
+	}
+
+	var victimOf func(K) bool
+	switch size := l.ext.namespaces.sizes[ns]; {
+	case size >= quota && borrowed >= unused:
+		victimOf = func(k K) bool {
+			return l.ext.namespaces.of(k) == ns
+		}
+	case size < quota && borrowed > 0 && borrowed >= unused:
+		victimOf = l.ext.namespaces.overQuota
+	default:
+		return nil
+	}
+	for link := l.elemList.Back(); ; link = link.Prev() {
+		if victimOf(link.Value.key) {
+			return link
+		}
+	}
+}
+
+// evictForNamespace evicts the element returned by namespaceVictim for the key
+func (l *cacheImpl[K, V]) evictForNamespace(link *linkedlist.Node[*element[K, V]], key K) *element[K, V] {
+	if l.ext.namespaces.of(link.Value.key) != l.ext.namespaces.of(key) {
+		l.record(statReclaim)
+	}
+	return l.evictLink(link)
+}
+
+// evictNamespaceWeight evicts the least frequently used elements of the namespace of the key except keep
+// until weight more fits in the max weight of the namespace and returns the first evicted element, or nil
+func (l *cacheImpl[K, V]) evictNamespaceWeight(key K, weight int64, keep *linkedlist.Node[*element[K, V]]) *element[K, V] {
+	ns := l.ext.namespaces.of(key)
+	maxWeight, ok := l.ext.namespaces.maxWeights[ns]
+	if !ok {
+		return nil
+	}
+	var first *element[K, V]
+	for link := l.elemList.Back(); l.ext.namespaces.weights[ns]+weight > maxWeight; {
+		prev := link.Prev()
+		if link != keep && l.ext.namespaces.of(link.Value.key) == ns {
+			if elem := l.evictLink(link); first == nil {
+				first = elem
+			}
+		}
+		link = prev
+	}
+	return first
+}
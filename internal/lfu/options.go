@@ -0,0 +1,212 @@
+package lfu
+
+import (
+	"errors"
+	"math/rand/v2"
+	"runtime"
+	"time"
+)
+
+var ErrInvalidOption = errors.New("invalid option")
+
+// Option configures optional behavior of the cache created by NewWithOptions.
+type Option[K comparable, V any] func(*cacheImpl[K, V]) error
+
+// NewWithOptions creates a cache of the given capacity configured with options.
+// Returns ErrInvalidCapacity if capacity is negative, ErrInvalidOption if an option is nil
+// or options needing each other are not combined, or the error of the first invalid option.
+func NewWithOptions[K comparable, V any](capacity int, opts ...Option[K, V]) (*cacheImpl[K, V], error) {
+	if capacity < 0 {
+		return nil, ErrInvalidCapacity
+	}
+	c := newCache[K, V](capacity)
+	for _, opt := range opts {
+		if opt == nil {
+			return nil, ErrInvalidOption
+		}
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.combine(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// combine checks the options depending on each other once all of them are applied and completes them
+func (c *cacheImpl[K, V]) combine() error {
+	x := c.ext
+	if x == nil {
+		return nil
+	}
+	if x.namespaceMaxWeights != nil {
+		if x.namespaces == nil || x.weigher == nil {
+			return ErrInvalidOption
+		}
+		x.namespaces.maxWeights = x.namespaceMaxWeights
+	}
+	if x.maxValueWeight > 0 && x.weigher == nil {
+		return ErrInvalidOption
+	}
+	if x.incrementInterval > 0 || x.logFrequency || x.weigher != nil || x.sampleRate > 0 || x.timestamps || x.ttl > 0 {
+		x.states = make(map[K]*entryState)
+	}
+	if x.trimInterval > 0 {
+		if x.softLimit == 0 {
+			return ErrInvalidOption
+		}
+		c.startTrimming()
+	}
+	return nil
+}
+
+// WithMemoryPressure enables soft-value mode. The cache stops holding its full capacity at all costs:
+// after every garbage collection the live heap size is compared with watermark (in bytes), and if it is
+// exceeded, the next Get or Put drops the least frequently used half of the entries.
+// The watcher stops when the cache is closed or garbage collected.
+func WithMemoryPressure[K comparable, V any](watermark uint64) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		x := c.extend()
+		if x.pressure != nil {
+			x.pressure.stop()
+		} else {
+			c.setFinalizer()
+		}
+		x.pressure = newPressureWatcher(watermark)
+		x.hooked = true
+		return nil
+	}
+}
+
+// setFinalizer makes the garbage collector stop the background work of the cache.
+// The finalizer is set once, so it does nothing if a background watcher is already set
+func (c *cacheImpl[K, V]) setFinalizer() {
+	if x := c.extend(); x.pressure != nil || x.reporter != nil {
+		return
+	}
+	runtime.SetFinalizer(c, func(c *cacheImpl[K, V]) {
+		if c.ext.pressure != nil {
+			c.ext.pressure.stop()
+		}
+		if c.ext.reporter != nil {
+			c.ext.reporter.stop()
+		}
+	})
+}
+
+// Close stops the background work of the cache: the stats reporter of WithStatsReporter,
+// the memory pressure watcher of WithMemoryPressure and the trimming of WithBackgroundTrim.
+// The cache stays usable, it only stops reporting, shedding and trimming in the background.
+// Closing a closed cache does nothing.
+//
+// O(1)
+func (c *cacheImpl[K, V]) Close() error {
+	x := c.ext
+	if x == nil {
+		return nil
+	}
+	if x.pressure != nil {
+		x.pressure.stop()
+		x.pressure = nil
+	}
+	if x.reporter != nil {
+		x.reporter.stop()
+		x.reporter = nil
+	}
+	if x.trimDone != nil {
+		close(x.trimDone)
+		x.trimDone = nil
+	}
+	x.hooked = x.recorder != nil || x.prefetched != nil
+	return nil
+}
+
+// WithMaxFrequency sets the frequency at which counters saturate. Accesses to an element with
+// the maximum frequency only refresh its recency and are counted in Stats.Saturations.
+// Returns ErrInvalidOption if maxFreq is not positive.
+func WithMaxFrequency[K comparable, V any](maxFreq int) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if maxFreq < 1 {
+			return ErrInvalidOption
+		}
+		c.maxFreq = maxFreq
+		return nil
+	}
+}
+
+// WithRandomTieBreak makes the cache evict a random element among the least frequently used ones
+// instead of the least recently used one. Random numbers are drawn from src, so eviction decisions
+// are reproducible with a seeded source. Returns ErrInvalidOption if src is nil.
+// Eviction becomes O(k), where k is the number of the least frequently used elements.
+func WithRandomTieBreak[K comparable, V any](src rand.Source) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if src == nil {
+			return ErrInvalidOption
+		}
+		c.extend().tieBreak = rand.New(src)
+		return nil
+	}
+}
+
+// WithIncrementInterval rate-limits frequency increments: the frequency of a key is increased
+// at most once per interval, so a single hot key cannot skew the statistics. Suppressed accesses
+// only refresh recency and are counted in Stats.SuppressedIncrements.
+// Returns ErrInvalidOption if interval is negative.
+func WithIncrementInterval[K comparable, V any](interval time.Duration) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if interval < 0 {
+			return ErrInvalidOption
+		}
+		c.extend().incrementInterval = interval.Nanoseconds()
+		return nil
+	}
+}
+
+// WithLogFrequency buckets frequencies logarithmically: an entry with frequency f moves to
+// the block 2f after f more accesses, so frequencies are powers of two (or MaxFrequency).
+// The number of blocks, and the block maintenance for hot keys, stays small, while eviction quality barely changes.
+func WithLogFrequency[K comparable, V any]() Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		c.extend().logFrequency = true
+		return nil
+	}
+}
+
+// WithMorrisCounter makes frequencies approximate, like the LFU policy of Redis does:
+// an entry with frequency f is incremented with probability 1/((f-1)*factor+1), otherwise only its recency
+// is refreshed. Most accesses of hot keys skip block maintenance, so frequencies grow much slower than
+// the number of accesses. Counters are not made smaller: WithMaxFrequency(255) limits frequencies
+// to the range of 8 bits, but they are still stored as int.
+// Random numbers are drawn from src, or from a randomly seeded source if src is nil.
+// Returns ErrInvalidOption if factor is not positive.
+func WithMorrisCounter[K comparable, V any](factor int, src rand.Source) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if factor < 1 {
+			return ErrInvalidOption
+		}
+		if src == nil {
+			src = rand.NewPCG(rand.Uint64(), rand.Uint64())
+		}
+		x := c.extend()
+		x.morris = rand.New(src)
+		x.morrisFactor = factor
+		return nil
+	}
+}
+
+// WithSampleRate makes only every n-th access of an entry move it between blocks: the other accesses
+// only count the access, so they neither refresh the recency nor change the eviction order.
+// The counted accesses are applied at once by the n-th access, or when the entry is about to be evicted.
+// GetKeyFrequency reports the frequency including the counted accesses. The option takes precedence
+// over WithIncrementInterval, WithLogFrequency and WithMorrisCounter.
+// Returns ErrInvalidOption if n is not positive.
+func WithSampleRate[K comparable, V any](n int) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if n < 1 {
+			return ErrInvalidOption
+		}
+		c.extend().sampleRate = n
+		return nil
+	}
+}
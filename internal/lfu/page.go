@@ -0,0 +1,36 @@
+package lfu
+
+// Cursor is the position of a page of the cache entries, the zero Cursor is the position of the first page
+type Cursor struct {
+	offset int
+	done   bool
+}
+
+// Done reports whether there are no more pages after the cursor
+func (c Cursor) Done() bool {
+	return c.done
+}
+
+// Page returns up to limit entries in the order of All starting at cursor and the cursor of the next page,
+// which is Done if there are no more entries. The cursor is an offset, so the cache can be modified
+// between pages: an entry staying in its position is returned exactly once, while entries accessed,
+// inserted or removed between pages can shift the others, which are then skipped or returned twice.
+//
+// O(offset of the cursor + limit)
+func (l *cacheImpl[K, V]) Page(cursor Cursor, limit int) ([]Entry[K, V], Cursor) {
+	if cursor.done || limit <= 0 {
+		return nil, cursor
+	}
+	entries := make([]Entry[K, V], 0, min(limit, max(0, l.elemList.Size()-cursor.offset)))
+	i := 0
+	for elem := range l.elemList.All() {
+		if i++; i <= cursor.offset {
+			continue
+		}
+		if len(entries) == limit {
+			return entries, Cursor{offset: cursor.offset + limit}
+		}
+		entries = append(entries, l.entry(elem))
+	}
+	return entries, Cursor{offset: cursor.offset + len(entries), done: true}
+}
@@ -0,0 +1,64 @@
+package lfu
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCallbackPanic = errors.New("callback panicked")
+
+// WithPanicHandler sets the handler invoked with an error wrapping ErrCallbackPanic when a user callback
+// panics. Panics of the eviction callbacks, the replication sink, the stats reporter, the loader,
+// the weigher, the index attributes, the namespace function, the interning functions and the redactor
+// are recovered whether the handler is set or not: the cache stays consistent, the other callbacks
+// of the operation are still invoked and a panicking loader call fails with the error.
+// A value whose weigher panics is rejected like a too heavy one, a value whose index attribute panics
+// is not indexed, a key whose namespace function panics belongs to the namespace "",
+// a value whose interning functions panic is not shared and a value whose redactor panics is dumped
+// as "[redactor panicked]".
+// The handler is invoked by the goroutine using the cache and must not panic itself.
+// Returns ErrInvalidOption if handler is nil.
+func WithPanicHandler[K comparable, V any](handler func(error)) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if handler == nil {
+			return ErrInvalidOption
+		}
+		c.extend().panicHandler = handler
+		return nil
+	}
+}
+
+// panicError describes the value recovered from a panic of the callback
+func panicError(callback string, recovered any) error {
+	return fmt.Errorf("%w: %s: %v", ErrCallbackPanic, callback, recovered)
+}
+
+// reportPanic counts the recovered panic and passes it to the panic handler
+func (l *cacheImpl[K, V]) reportPanic(err error) {
+	l.record(statCallbackPanic)
+	if l.ext != nil && l.ext.panicHandler != nil {
+		l.ext.panicHandler(err)
+	}
+}
+
+// guarded calls the user function f and returns its result and nil, or, if f panics,
+// the zero value and the error reported with report
+func guarded[T any](report func(error), callback string, f func() T) (result T, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = panicError(callback, recovered)
+			report(err)
+		}
+	}()
+	return f(), nil
+}
+
+// safely invokes the callback and recovers its panic
+func (l *cacheImpl[K, V]) safely(callback string, f func()) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			l.reportPanic(panicError(callback, recovered))
+		}
+	}()
+	f()
+}
@@ -0,0 +1,112 @@
+package lfu
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultPrefetchConcurrency is the default maximum number of loader calls made by Prefetch at once
+const DefaultPrefetchConcurrency = 4
+
+// prefetchResult is the outcome of a loader call made by Prefetch
+type prefetchResult[K comparable, V any] struct {
+	values map[K]V
+	err    error
+}
+
+// WithPrefetchConcurrency sets the maximum number of loader calls made by Prefetch at once.
+// Returns ErrInvalidOption if n is not positive.
+func WithPrefetchConcurrency[K comparable, V any](n int) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if n < 1 {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		x.prefetchSlots = make(chan struct{}, n)
+		x.prefetched = make(chan prefetchResult[K, V], n)
+		x.hooked = true
+		return nil
+	}
+}
+
+// Prefetch starts loading the keys missing in the cache with the configured BulkLoader
+// in the background and returns immediately. The cache is not accessed concurrently:
+// the loaded values are put into the cache by the next Get or Put, keys put in the meantime
+// keep their values. The prefetch is dropped if the cache has no loader, if the limit
+// set by WithPrefetchConcurrency is reached or if the circuit of the loader is open.
+// Loader errors are ignored, except for being counted by the circuit breaker.
+//
+// O(len(keys))
+func (l *cacheImpl[K, V]) Prefetch(ctx context.Context, keys []K) {
+	if l.ext == nil || l.ext.loader == nil {
+		return
+	}
+	if l.ext.prefetchSlots == nil {
+		l.ext.prefetchSlots = make(chan struct{}, DefaultPrefetchConcurrency)
+		l.ext.prefetched = make(chan prefetchResult[K, V], DefaultPrefetchConcurrency)
+		l.ext.hooked = true
+	}
+	l.applyPrefetched()
+
+	pending := make(map[K]struct{})
+	var missing []K
+	for _, key := range keys {
+		if _, ok := l.keyToElement[key]; ok {
+			continue
+		}
+		if _, ok := pending[key]; ok {
+			continue
+		}
+		pending[key] = struct{}{}
+		missing = append(missing, key)
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	select {
+	case l.ext.prefetchSlots <- struct{}{}:
+	default:
+		return
+	}
+	if l.ext.breaker != nil && !l.ext.breaker.allow(l.clock()) {
+		<-l.ext.prefetchSlots
+		return
+	}
+	// the slot is released when the result is applied, so the send never blocks
+	go func(loader BulkLoader[K, V], results chan<- prefetchResult[K, V]) {
+		loaded, err := load(ctx, loader, missing)
+		results <- prefetchResult[K, V]{values: loaded, err: err}
+	}(l.ext.loader, l.ext.prefetched)
+}
+
+// applyPrefetched puts the values loaded by finished prefetches into the cache.
+// Keys put into the cache while they were being loaded keep their values
+func (l *cacheImpl[K, V]) applyPrefetched() {
+	var results []prefetchResult[K, V]
+	for done := false; !done; {
+		select {
+		case result := <-l.ext.prefetched:
+			<-l.ext.prefetchSlots
+			if errors.Is(result.err, ErrCallbackPanic) {
+				l.reportPanic(result.err)
+			}
+			if l.ext.breaker != nil {
+				l.ext.breaker.record(result.err, l.clock())
+			}
+			results = append(results, result)
+		default:
+			done = true
+		}
+	}
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		for key, value := range result.values {
+			if _, ok := l.keyToElement[key]; !ok {
+				l.put(key, value)
+			}
+		}
+	}
+}
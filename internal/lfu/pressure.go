@@ -0,0 +1,53 @@
+package lfu
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+)
+
+const liveHeapMetric = "/gc/heap/live:bytes"
+
+// pressureWatcher observes garbage collections with a finalizer-based sentinel
+// and reports whether the live heap exceeded the watermark after the last one.
+// The finalizer runs on the runtime goroutine, so the state is kept in atomics.
+type pressureWatcher struct {
+	watermark uint64
+	pressured atomic.Bool
+	stopped   atomic.Bool
+}
+
+// gcSentinel is an object that becomes unreachable right after it is allocated,
+// so its finalizer runs once per garbage collection cycle.
+type gcSentinel struct {
+	watcher *pressureWatcher
+}
+
+func newPressureWatcher(watermark uint64) *pressureWatcher {
+	w := &pressureWatcher{watermark: watermark}
+	w.arm()
+	return w
+}
+
+func (w *pressureWatcher) arm() {
+	runtime.SetFinalizer(&gcSentinel{watcher: w}, func(s *gcSentinel) {
+		s.watcher.collected()
+	})
+}
+
+func (w *pressureWatcher) collected() {
+	if w.stopped.Load() {
+		return
+	}
+	sample := []metrics.Sample{{Name: liveHeapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 && sample[0].Value.Uint64() > w.watermark {
+		w.pressured.Store(true)
+	}
+	w.arm()
+}
+
+// stop prevents the sentinel from being rearmed, so the watcher can be collected
+func (w *pressureWatcher) stop() {
+	w.stopped.Store(true)
+}
@@ -0,0 +1,46 @@
+package lfu
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Rank returns the position of the key in eviction order, 0 for the next victim, if the key exists
+// in the cache, otherwise, returns ErrKeyNotFound. Keys with the same frequency are ranked by recency,
+// even if the victim among them is chosen randomly because of WithRandomTieBreak.
+// The accesses counted but not applied yet because of WithSampleRate are included.
+//
+// O(number of distinct frequencies + number of keys with the same frequency),
+// or O(capacity * log(capacity)) if WithSampleRate is set
+func (l *cacheImpl[K, V]) Rank(key K) (int, error) {
+	link, ok := l.keyToElement[key]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	if l.ext != nil && l.ext.sampleRate > 0 {
+		return slices.Index(l.evictionOrder(), link.Value), nil
+	}
+	freq := link.Value.freq
+	rank := 0
+	for f, count := range l.freqToCount {
+		if f < freq {
+			rank += count
+		}
+	}
+	for next := link.Next(); next != l.elemList.Head() && next.Value.freq == freq; next = next.Next() {
+		rank++
+	}
+	return rank, nil
+}
+
+// evictionOrder returns the elements in the order they would be evicted, including the pending accesses
+// of sampled elements. victim applies them from the back: an element with pending accesses moves to the front
+// of the block of its frequency including them, after the elements already there and the ones moved before it.
+// O(capacity * log(capacity))
+func (l *cacheImpl[K, V]) evictionOrder() []*element[K, V] {
+	order := slices.Collect(l.elemList.Backward())
+	slices.SortStableFunc(order, func(a, b *element[K, V]) int {
+		return cmp.Or(cmp.Compare(l.frequency(a), l.frequency(b)), cmp.Compare(min(l.pending(a), 1), min(l.pending(b), 1)))
+	})
+	return order
+}
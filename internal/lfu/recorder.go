@@ -0,0 +1,51 @@
+package lfu
+
+import (
+	"fmt"
+	"hash/maphash"
+	"io"
+)
+
+// Operations written by the recorder set by WithRecorder
+const (
+	RecordGet    = 'G'
+	RecordPut    = 'P'
+	RecordRemove = 'R'
+)
+
+// recorder writes the trace of operations, a line per operation
+type recorder[K comparable] struct {
+	w        io.Writer
+	hashKeys bool
+	seed     maphash.Seed
+	err      error
+}
+
+// WithRecorder streams every Get, Put and Remove to w for offline replay, including those made
+// by the other methods, e.g. GetMulti or Apply. Every operation is written as a line of the operation
+// letter (RecordGet, RecordPut or RecordRemove), a space and the key formatted with %v, or its 64-bit hash
+// in hexadecimal if hashKeys is set. Hashes are stable for the lifetime of the cache only.
+// Writes are not buffered, and recording stops at the first write error.
+// Returns ErrInvalidOption if w is nil.
+func WithRecorder[K comparable, V any](w io.Writer, hashKeys bool) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if w == nil {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		x.recorder = &recorder[K]{w: w, hashKeys: hashKeys, seed: maphash.MakeSeed()}
+		x.hooked = true
+		return nil
+	}
+}
+
+func (r *recorder[K]) record(op byte, key K) {
+	if r.err != nil {
+		return
+	}
+	if r.hashKeys {
+		_, r.err = fmt.Fprintf(r.w, "%c %016x\n", op, maphash.Comparable(r.seed, key))
+	} else {
+		_, r.err = fmt.Fprintf(r.w, "%c %v\n", op, key)
+	}
+}
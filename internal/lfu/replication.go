@@ -0,0 +1,27 @@
+package lfu
+
+// WithReplication makes the cache stream its changes to followers: sink is invoked with OpPut
+// for every inserted or updated entry, including the entries copied by WarmFrom and SyncFrom,
+// with OpRemove for every entry removed by Remove, Apply, SyncFrom or a rejected Put, and with OpClear
+// when the cache is cleared, after the cache is updated. Evictions are not streamed, since followers
+// evict by their own policy. A follower applies the operations with Apply, e.g. from a goroutine
+// receiving them through a channel. Returns ErrInvalidOption if sink is nil.
+func WithReplication[K comparable, V any](sink func(Op[K, V])) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if sink == nil {
+			return ErrInvalidOption
+		}
+		c.extend().replicate = sink
+		return nil
+	}
+}
+
+// replicateOp passes the operation to the replication sink,
+// or postpones it until the operations in progress finish
+func (l *cacheImpl[K, V]) replicateOp(op Op[K, V]) {
+	if l.ext.deferDepth > 0 {
+		l.ext.deferred = append(l.ext.deferred, event[K, V]{op: op, replicated: true})
+		return
+	}
+	l.safely("replication sink", func() { l.ext.replicate(op) })
+}
@@ -0,0 +1,65 @@
+package lfu
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// statsReporter marks reports as due on an interval. Reports are made by the goroutine using the cache,
+// so the ticking goroutine only touches the atomic flag and does not keep the cache reachable.
+type statsReporter struct {
+	report func(Stats)
+	due    atomic.Bool
+	done   chan struct{}
+}
+
+// WithStatsReporter makes the cache invoke report with a snapshot of the statistics every interval.
+// The cache is not thread-safe, so report is invoked by the first Get or Put after the interval passes,
+// and an idle cache does not report. The ticking goroutine stops when the cache is closed
+// or garbage collected.
+// Returns ErrInvalidOption if interval is not positive or report is nil.
+func WithStatsReporter[K comparable, V any](interval time.Duration, report func(Stats)) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if interval <= 0 || report == nil {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		if x.reporter != nil {
+			x.reporter.stop()
+		} else {
+			c.setFinalizer()
+		}
+		x.reporter = newStatsReporter(interval, report)
+		x.hooked = true
+		return nil
+	}
+}
+
+func newStatsReporter(interval time.Duration, report func(Stats)) *statsReporter {
+	r := &statsReporter{report: report, done: make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+				r.due.Store(true)
+			}
+		}
+	}()
+	return r
+}
+
+func (r *statsReporter) stop() {
+	close(r.done)
+}
+
+// reportStats invokes the stats reporter if a report is due
+func (l *cacheImpl[K, V]) reportStats() {
+	if l.ext.reporter.due.Swap(false) {
+		stats := l.Stats()
+		l.safely("stats reporter", func() { l.ext.reporter.report(stats) })
+	}
+}
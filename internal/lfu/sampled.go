@@ -0,0 +1,560 @@
+package lfu
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// DefaultSamples is the default number of entries sampled on eviction by the sampled cache
+const DefaultSamples = 5
+
+// sampledImpl represents approximate LFU cache implementation in the style of Redis.
+// It does not maintain the frequency order: accesses only update counters of entries,
+// and on eviction samples random entries and evicts the one with the lowest decayed frequency,
+// the least recently used of them if there is a tie. Methods listing entries in order sort them.
+// The structure of sampled cache is:
+// 1. entries - slice of all entries for random sampling, every entry knows its index
+// 2. keyToEntry - map to get entry by using its key
+// 3. samples - number of entries sampled on eviction
+// 4. rnd - random generator for sampling
+// 5. clock - number of accesses, used as the time of the last access of entries
+// 6. decay - period in nanoseconds after which the frequency of an idle entry is decreased by one, 0 if it is not
+// 7. now - current time in nanoseconds since the creation of the cache
+// 8. generation - number of changes of the cache
+// 9. batching - an Apply is in progress, so notifications are postponed until it finishes
+// 10. deferred - notifications postponed until the Apply in progress finishes
+// 11. panics - number of panics recovered from the callbacks, reported as Stats.CallbackPanics
+type sampledImpl[K comparable, V any] struct {
+	entries    []*sampledEntry[K, V]
+	keyToEntry map[K]*sampledEntry[K, V]
+	capacity   int
+	samples    int
+	rnd        *rand.Rand
+	clock      uint64
+	decay      int64
+	now        func() int64
+	generation uint64
+	batching   bool
+	deferred   []event[K, V]
+	panics     uint64
+}
+
+type sampledEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	freq     int
+	lastUsed uint64
+	// usedAt is the time of the last access in nanoseconds, the frequency decays from it
+	usedAt  int64
+	index   int
+	onEvict func(Entry[K, V], Reason)
+	meta    any
+}
+
+// NewSampled creates an approximate LFU cache of the given capacity, which samples the given number
+// of entries on eviction. The more samples, the closer eviction is to the exact LFU and the slower it is.
+// The frequency of an entry decreases by one every decay it is not accessed, like with lfu-decay-time
+// of Redis, so entries that were hot once do not stay in the cache forever; 0 disables decay.
+// Random numbers are drawn from src, or from a randomly seeded source if src is nil.
+// Options of NewWithOptions are not supported, neither are the methods depending on them, like LoadMulti or Age.
+// Returns ErrInvalidCapacity if capacity is negative, or ErrInvalidOption if samples is not positive
+// or decay is negative.
+func NewSampled[K comparable, V any](capacity, samples int, decay time.Duration, src rand.Source) (*sampledImpl[K, V], error) {
+	if capacity < 0 {
+		return nil, ErrInvalidCapacity
+	}
+	if samples < 1 || decay < 0 {
+		return nil, ErrInvalidOption
+	}
+	if src == nil {
+		src = rand.NewPCG(rand.Uint64(), rand.Uint64())
+	}
+	return &sampledImpl[K, V]{
+		entries:    make([]*sampledEntry[K, V], 0, capacity),
+		keyToEntry: make(map[K]*sampledEntry[K, V], capacity),
+		capacity:   capacity,
+		samples:    samples,
+		rnd:        rand.New(src),
+		decay:      decay.Nanoseconds(),
+		now:        monotonicClock(),
+	}, nil
+}
+
+// frequency returns the frequency of the entry decayed by the time it has not been accessed for
+func (s *sampledImpl[K, V]) frequency(entry *sampledEntry[K, V], now int64) int {
+	if s.decay == 0 {
+		return entry.freq
+	}
+	periods := (now - entry.usedAt) / s.decay
+	if periods >= int64(entry.freq) {
+		return 0
+	}
+	return entry.freq - int(periods)
+}
+
+func (s *sampledImpl[K, V]) use(entry *sampledEntry[K, V]) {
+	now := s.now()
+	s.clock++
+	entry.lastUsed = s.clock
+	if freq := s.frequency(entry, now); freq < MaxFrequency {
+		entry.freq = freq + 1
+	} else {
+		entry.freq = freq
+	}
+	entry.usedAt = now
+}
+
+// compareSampled orders entries in eviction order: by decayed frequency, then by recency
+func (s *sampledImpl[K, V]) compareSampled(a, b *sampledEntry[K, V], now int64) int {
+	return cmp.Or(cmp.Compare(s.frequency(a, now), s.frequency(b, now)), cmp.Compare(a.lastUsed, b.lastUsed))
+}
+
+// sorted returns the entries in eviction order
+func (s *sampledImpl[K, V]) sorted() []*sampledEntry[K, V] {
+	now := s.now()
+	return slices.SortedFunc(slices.Values(s.entries), func(a, b *sampledEntry[K, V]) int {
+		return s.compareSampled(a, b, now)
+	})
+}
+
+// victim returns the least frequently used of the sampled entries
+func (s *sampledImpl[K, V]) victim() *sampledEntry[K, V] {
+	now := s.now()
+	victim := s.entries[s.rnd.IntN(len(s.entries))]
+	for range s.samples - 1 {
+		if candidate := s.entries[s.rnd.IntN(len(s.entries))]; s.compareSampled(candidate, victim, now) < 0 {
+			victim = candidate
+		}
+	}
+	return victim
+}
+
+// remove deletes the entry by moving the last entry of the slice in its place and invokes its callback
+func (s *sampledImpl[K, V]) remove(entry *sampledEntry[K, V], reason Reason) {
+	last := s.entries[len(s.entries)-1]
+	last.index = entry.index
+	s.entries[entry.index] = last
+	s.entries[len(s.entries)-1] = nil
+	s.entries = s.entries[:len(s.entries)-1]
+	delete(s.keyToEntry, entry.key)
+	s.generation++
+	s.notify(entry, entry.value, reason)
+}
+
+// notify invokes the callback set by PutWithCallback, or postpones it until the Apply in progress finishes
+func (s *sampledImpl[K, V]) notify(entry *sampledEntry[K, V], value V, reason Reason) {
+	if entry.onEvict == nil {
+		return
+	}
+	callback := entry.onEvict
+	entry.onEvict = nil
+	notified := Entry[K, V]{Key: entry.key, Value: value, Frequency: s.frequency(entry, s.now()), Meta: entry.meta}
+	if s.batching {
+		s.deferred = append(s.deferred, event[K, V]{entry: notified, reason: reason, callback: callback})
+		return
+	}
+	s.invoke(callback, notified, reason)
+}
+
+// invoke invokes the callback, counting its panic instead of propagating it
+func (s *sampledImpl[K, V]) invoke(callback func(Entry[K, V], Reason), entry Entry[K, V], reason Reason) {
+	defer func() {
+		if recover() != nil {
+			s.panics++
+		}
+	}()
+	callback(entry, reason)
+}
+
+// insert adds the key with the given frequency, evicting a sampled entry if the cache is full.
+// If the sampled entry is hotter than freq and evict is not set, the key is not inserted and nil is returned
+func (s *sampledImpl[K, V]) insert(key K, value V, freq int, evict bool) (*sampledEntry[K, V], *sampledEntry[K, V]) {
+	if s.capacity == 0 {
+		return nil, nil
+	}
+	var victim *sampledEntry[K, V]
+	if len(s.entries) == s.capacity {
+		victim = s.victim()
+		if !evict && s.frequency(victim, s.now()) > freq {
+			return nil, nil
+		}
+		s.remove(victim, ReasonEvicted)
+	}
+	s.clock++
+	s.generation++
+	entry := &sampledEntry[K, V]{key: key, value: value, freq: freq, lastUsed: s.clock, usedAt: s.now(), index: len(s.entries)}
+	s.entries = append(s.entries, entry)
+	s.keyToEntry[key] = entry
+	return entry, victim
+}
+
+// put updates or inserts the key and returns the entry evicted to make room for it, or nil
+func (s *sampledImpl[K, V]) put(key K, value V) *sampledEntry[K, V] {
+	if entry, ok := s.keyToEntry[key]; ok {
+		s.use(entry)
+		old := entry.value
+		entry.value = value
+		s.generation++
+		s.notify(entry, old, ReasonReplaced)
+		return nil
+	}
+	_, victim := s.insert(key, value, 1, true)
+	return victim
+}
+
+func (s *sampledImpl[K, V]) Get(key K) (V, error) {
+	if entry, ok := s.keyToEntry[key]; ok {
+		s.use(entry)
+		return entry.value, nil
+	}
+	var zero V
+	return zero, ErrKeyNotFound
+}
+
+// Put updates the value of the key if present, or inserts the key if not already present.
+// When the cache reaches its capacity, it evicts the least frequently used of the sampled keys.
+//
+// O(samples)
+func (s *sampledImpl[K, V]) Put(key K, value V) {
+	s.put(key, value)
+}
+
+func (s *sampledImpl[K, V]) PutWithEvicted(key K, value V) (K, V, bool) {
+	if victim := s.put(key, value); victim != nil {
+		return victim.key, victim.value, true
+	}
+	var (
+		zeroKey   K
+		zeroValue V
+	)
+	return zeroKey, zeroValue, false
+}
+
+func (s *sampledImpl[K, V]) PutTransient(key K, value V) {
+	if entry, ok := s.keyToEntry[key]; ok {
+		s.remove(entry, ReasonReplaced)
+	}
+	s.insert(key, value, 0, false)
+}
+
+func (s *sampledImpl[K, V]) PutWithCallback(key K, value V, onEvict func(Entry[K, V], Reason)) {
+	s.put(key, value)
+	if onEvict == nil {
+		return
+	}
+	entry, ok := s.keyToEntry[key]
+	if !ok {
+		s.notify(&sampledEntry[K, V]{key: key, onEvict: onEvict}, value, ReasonEvicted)
+		return
+	}
+	entry.onEvict = onEvict
+}
+
+func (s *sampledImpl[K, V]) PutWithMeta(key K, value V, meta any) {
+	s.put(key, value)
+	if entry, ok := s.keyToEntry[key]; ok {
+		entry.meta = meta
+	}
+}
+
+// entry describes the entry
+func (s *sampledImpl[K, V]) entry(entry *sampledEntry[K, V], now int64) Entry[K, V] {
+	return Entry[K, V]{Key: entry.key, Value: entry.value, Frequency: s.frequency(entry, now), Meta: entry.meta}
+}
+
+func (s *sampledImpl[K, V]) EntryInfo(key K) (Entry[K, V], error) {
+	if entry, ok := s.keyToEntry[key]; ok {
+		return s.entry(entry, s.now()), nil
+	}
+	return Entry[K, V]{}, ErrKeyNotFound
+}
+
+func (s *sampledImpl[K, V]) Remove(key K) error {
+	entry, ok := s.keyToEntry[key]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	s.remove(entry, ReasonRemoved)
+	return nil
+}
+
+func (s *sampledImpl[K, V]) Apply(ops []Op[K, V]) error {
+	for _, op := range ops {
+		if op.Kind != OpPut && op.Kind != OpRemove && op.Kind != OpClear {
+			return ErrInvalidOp
+		}
+	}
+	s.batching = true
+	for _, op := range ops {
+		switch op.Kind {
+		case OpPut:
+			s.put(op.Key, op.Value)
+		case OpRemove:
+			_ = s.Remove(op.Key)
+		case OpClear:
+			s.Clear()
+		}
+	}
+	s.batching = false
+	deferred := s.deferred
+	s.deferred = nil
+	for _, e := range deferred {
+		s.invoke(e.callback, e.entry, e.reason)
+	}
+	return nil
+}
+
+func (s *sampledImpl[K, V]) GetMulti(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, err := s.Get(key); err == nil {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+func (s *sampledImpl[K, V]) PutMulti(entries map[K]V) {
+	for key, value := range entries {
+		s.put(key, value)
+	}
+}
+
+func (s *sampledImpl[K, V]) Generation() uint64 {
+	return s.generation
+}
+
+func (s *sampledImpl[K, V]) ChangesSince(gen uint64) ([]Change[K], error) {
+	if gen == s.generation {
+		return nil, nil
+	}
+	return nil, ErrHistoryTruncated
+}
+
+func (s *sampledImpl[K, V]) WarmFrom(src WarmSource[K, V], limit int) (int, error) {
+	if src == nil {
+		return 0, ErrNilSource
+	}
+	if limit <= 0 || limit > s.capacity {
+		limit = s.capacity
+	}
+	copied := 0
+	for _, entry := range hottest(src, limit) {
+		if s.insertWithFreq(entry.key, entry.value, entry.freq) {
+			copied++
+		}
+	}
+	return copied, nil
+}
+
+// insertWithFreq replaces the key with the given frequency unless the cache is full of hotter entries
+func (s *sampledImpl[K, V]) insertWithFreq(key K, value V, freq int) bool {
+	if entry, ok := s.keyToEntry[key]; ok {
+		s.remove(entry, ReasonReplaced)
+	}
+	entry, _ := s.insert(key, value, max(0, freq), false)
+	return entry != nil
+}
+
+func (s *sampledImpl[K, V]) SyncFrom(src SyncSource[K, V], since uint64) (uint64, error) {
+	if src == nil {
+		return since, ErrNilSource
+	}
+	gen := src.Generation()
+	changes, err := src.ChangesSince(since)
+	if warm, ok := src.(WarmSource[K, V]); ok && errors.Is(err, ErrHistoryTruncated) {
+		s.Clear()
+		_, _ = s.WarmFrom(warm, 0)
+		return gen, nil
+	}
+	if err != nil {
+		return since, err
+	}
+	for _, change := range changes {
+		entry, err := src.EntryInfo(change.Key)
+		if change.Removed || err != nil {
+			_ = s.Remove(change.Key)
+			continue
+		}
+		s.insertWithFreq(entry.Key, entry.Value, entry.Frequency)
+	}
+	return gen, nil
+}
+
+// Rank returns the position of the key in the order of EvictionOrder if the key exists in the cache,
+// otherwise, returns ErrKeyNotFound. Eviction samples entries, so it is not the exact position of the next victim.
+//
+// O(capacity)
+func (s *sampledImpl[K, V]) Rank(key K) (int, error) {
+	entry, ok := s.keyToEntry[key]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	now := s.now()
+	rank := 0
+	for _, other := range s.entries {
+		if s.compareSampled(other, entry, now) < 0 {
+			rank++
+		}
+	}
+	return rank, nil
+}
+
+// All returns the iterator in descending order of decayed frequency.
+// If two or more keys have the same frequency, the most recently used key will be listed first.
+//
+// O(capacity * log(capacity))
+func (s *sampledImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sorted := s.sorted()
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if !yield(sorted[i].key, sorted[i].value) {
+				return
+			}
+		}
+	}
+}
+
+// EvictionOrder returns the iterator in the reverse order of All.
+//
+// O(capacity * log(capacity))
+func (s *sampledImpl[K, V]) EvictionOrder() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, entry := range s.sorted() {
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// Page works like Cache.Page.
+//
+// O(capacity * log(capacity))
+func (s *sampledImpl[K, V]) Page(cursor Cursor, limit int) ([]Entry[K, V], Cursor) {
+	if cursor.done || limit <= 0 {
+		return nil, cursor
+	}
+	sorted := s.sorted()
+	slices.Reverse(sorted)
+	now := s.now()
+	entries := make([]Entry[K, V], 0, min(limit, max(0, len(sorted)-cursor.offset)))
+	for _, entry := range sorted[min(cursor.offset, len(sorted)):] {
+		if len(entries) == limit {
+			return entries, Cursor{offset: cursor.offset + limit}
+		}
+		entries = append(entries, s.entry(entry, now))
+	}
+	return entries, Cursor{offset: cursor.offset + len(entries), done: true}
+}
+
+func (s *sampledImpl[K, V]) Size() int {
+	return len(s.entries)
+}
+
+func (s *sampledImpl[K, V]) Capacity() int {
+	return s.capacity
+}
+
+func (s *sampledImpl[K, V]) Available() int {
+	return s.capacity - len(s.entries)
+}
+
+func (s *sampledImpl[K, V]) Clear() {
+	cleared := slices.Clone(s.entries)
+	clear(s.entries)
+	s.entries = s.entries[:0]
+	clear(s.keyToEntry)
+	s.generation++
+	for _, entry := range cleared {
+		s.notify(entry, entry.value, ReasonCleared)
+	}
+}
+
+func (s *sampledImpl[K, V]) GetKeyFrequency(key K) (int, error) {
+	if entry, ok := s.keyToEntry[key]; ok {
+		return s.frequency(entry, s.now()), nil
+	}
+	return 0, ErrKeyNotFound
+}
+
+// Stats returns a snapshot of the cache statistics, the blocks are the groups of entries
+// with the same decayed frequency. Only the panics of the callbacks are counted.
+//
+// O(capacity)
+func (s *sampledImpl[K, V]) Stats() Stats {
+	if len(s.entries) == 0 {
+		return Stats{CallbackPanics: s.panics}
+	}
+	now := s.now()
+	counts := make(map[int]int)
+	stats := Stats{LowestFrequency: math.MaxInt, CallbackPanics: s.panics}
+	for _, entry := range s.entries {
+		freq := s.frequency(entry, now)
+		counts[freq]++
+		stats.LowestFrequency = min(stats.LowestFrequency, freq)
+		stats.HighestFrequency = max(stats.HighestFrequency, freq)
+	}
+	stats.Blocks = len(counts)
+	stats.LowestBlockSize = counts[stats.LowestFrequency]
+	stats.HighestBlockSize = counts[stats.HighestFrequency]
+	return stats
+}
+
+func (s *sampledImpl[K, V]) DebugDump(w io.Writer) error {
+	now := s.now()
+	sorted := s.sorted()
+	for i := len(sorted) - 1; i >= 0; i-- {
+		entry := sorted[i]
+		if _, err := fmt.Fprintf(w, "%v\t%d\t%v\n", entry.key, s.frequency(entry, now), entry.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportDOT writes the slice of entries sampled on eviction as a Graphviz DOT graph,
+// every entry labeled with its key, decayed frequency and value.
+//
+// O(capacity)
+func (s *sampledImpl[K, V]) ExportDOT(w io.Writer) error {
+	now := s.now()
+	out := &errWriter{w: w}
+	out.printf("digraph lfu {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for i, entry := range s.entries {
+		out.printf("\te%d [label=%q];\n", i, fmt.Sprintf("%v\nfrequency %d\n%v", entry.key, s.frequency(entry, now), entry.value))
+		if i > 0 {
+			out.printf("\te%d -> e%d;\n", i-1, i)
+		}
+	}
+	out.printf("}\n")
+	return out.err
+}
+
+func (s *sampledImpl[K, V]) CheckInvariants() error {
+	if len(s.entries) != len(s.keyToEntry) {
+		return fmt.Errorf("%w: %d entries, key map size %d", ErrInvariantViolated, len(s.entries), len(s.keyToEntry))
+	}
+	if len(s.entries) > s.capacity {
+		return fmt.Errorf("%w: size %d exceeds capacity %d", ErrInvariantViolated, len(s.entries), s.capacity)
+	}
+	for i, entry := range s.entries {
+		if entry.index != i {
+			return fmt.Errorf("%w: key %v at index %d knows index %d", ErrInvariantViolated, entry.key, i, entry.index)
+		}
+		if s.keyToEntry[entry.key] != entry {
+			return fmt.Errorf("%w: key %v is not mapped to its entry", ErrInvariantViolated, entry.key)
+		}
+		if entry.freq < 0 {
+			return fmt.Errorf("%w: key %v has frequency %d", ErrInvariantViolated, entry.key, entry.freq)
+		}
+	}
+	return nil
+}
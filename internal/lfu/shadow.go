@@ -0,0 +1,326 @@
+package lfu
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// HitStats counts the hits and misses of Get
+type HitStats struct {
+	Hits, Misses uint64
+}
+
+// HitRate returns the share of hits among all Get calls, or 0 if there were none
+func (s HitStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// shadowedImpl mirrors the operations of the primary cache into shadow caches, which store keys only,
+// to compare hit rates of different policies and capacities on the same traffic.
+// Methods not overridden here are served by the primary cache alone.
+type shadowedImpl[K comparable, V any] struct {
+	Replica[K, V]
+	hits       HitStats
+	shadows    map[string]Replica[K, struct{}]
+	shadowHits map[string]*HitStats
+}
+
+// NewShadowed wraps the primary cache, so Get, GetMulti, LoadMulti, Put, PutWithEvicted, PutTransient,
+// PutMulti, Apply, Remove, Clear, WarmFrom and SyncFrom are mirrored into the named shadow caches.
+// A key missing only in a shadow is put into it by Get and LoadMulti, as if it was loaded.
+// Prefetch is not mirrored, since its loads finish later, a prefetched key gets into the shadows
+// on its first access. The hit rates of the primary cache and of the shadows are reported by Stats and HitStats.
+// Returns ErrInvalidOption if primary or a shadow is nil.
+func NewShadowed[K comparable, V any](primary Replica[K, V], shadows map[string]Replica[K, struct{}]) (*shadowedImpl[K, V], error) {
+	if primary == nil {
+		return nil, ErrInvalidOption
+	}
+	s := &shadowedImpl[K, V]{
+		Replica:    primary,
+		shadows:    make(map[string]Replica[K, struct{}], len(shadows)),
+		shadowHits: make(map[string]*HitStats, len(shadows)),
+	}
+	for name, shadow := range shadows {
+		if shadow == nil {
+			return nil, ErrInvalidOption
+		}
+		s.shadows[name] = shadow
+		s.shadowHits[name] = &HitStats{}
+	}
+	return s, nil
+}
+
+// HitStats returns the hit statistics of the primary cache and of every shadow by its name
+func (s *shadowedImpl[K, V]) HitStats() (HitStats, map[string]HitStats) {
+	shadows := make(map[string]HitStats, len(s.shadowHits))
+	for name, hits := range s.shadowHits {
+		shadows[name] = *hits
+	}
+	return s.hits, shadows
+}
+
+// Stats returns the statistics of the primary cache together with the hit statistics
+// of the primary cache and of every shadow
+func (s *shadowedImpl[K, V]) Stats() Stats {
+	stats := s.Replica.Stats()
+	stats.Hits, stats.ShadowHits = s.HitStats()
+	return stats
+}
+
+func (s *shadowedImpl[K, V]) Get(key K) (V, error) {
+	value, err := s.Replica.Get(key)
+	if err == nil {
+		s.hits.Hits++
+	} else {
+		s.hits.Misses++
+	}
+	for name, shadow := range s.shadows {
+		if _, shadowErr := shadow.Get(key); shadowErr == nil {
+			s.shadowHits[name].Hits++
+		} else {
+			s.shadowHits[name].Misses++
+			if err == nil {
+				shadow.Put(key, struct{}{})
+			}
+		}
+	}
+	return value, err
+}
+
+// LoadMulti works like LoadMulti of the primary cache. Returns ErrNoLoader if the primary cache cannot load keys
+func (s *shadowedImpl[K, V]) LoadMulti(ctx context.Context, keys []K) (map[K]V, error) {
+	loader, ok := s.Replica.(MultiLoader[K, V])
+	if !ok {
+		return nil, ErrNoLoader
+	}
+	seen := make(map[K]struct{}, len(keys))
+	distinct := make([]K, 0, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		distinct = append(distinct, key)
+		if _, err := s.Replica.EntryInfo(key); err == nil {
+			s.hits.Hits++
+		} else {
+			s.hits.Misses++
+		}
+	}
+	values, err := loader.LoadMulti(ctx, keys)
+	for name, shadow := range s.shadows {
+		for _, key := range distinct {
+			if _, shadowErr := shadow.Get(key); shadowErr == nil {
+				s.shadowHits[name].Hits++
+				continue
+			}
+			s.shadowHits[name].Misses++
+			if _, ok := values[key]; ok {
+				shadow.Put(key, struct{}{})
+			}
+		}
+	}
+	return values, err
+}
+
+func (s *shadowedImpl[K, V]) GetMulti(keys []K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, err := s.Get(key); err == nil {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+func (s *shadowedImpl[K, V]) mirrorPut(key K) {
+	for _, shadow := range s.shadows {
+		shadow.Put(key, struct{}{})
+	}
+}
+
+func (s *shadowedImpl[K, V]) Put(key K, value V) {
+	s.Replica.Put(key, value)
+	s.mirrorPut(key)
+}
+
+func (s *shadowedImpl[K, V]) PutWithEvicted(key K, value V) (K, V, bool) {
+	s.mirrorPut(key)
+	return s.Replica.PutWithEvicted(key, value)
+}
+
+func (s *shadowedImpl[K, V]) PutTransient(key K, value V) {
+	s.Replica.PutTransient(key, value)
+	for _, shadow := range s.shadows {
+		shadow.PutTransient(key, struct{}{})
+	}
+}
+
+func (s *shadowedImpl[K, V]) PutMulti(entries map[K]V) {
+	for key, value := range entries {
+		s.Put(key, value)
+	}
+}
+
+func (s *shadowedImpl[K, V]) Apply(ops []Op[K, V]) error {
+	if err := s.Replica.Apply(ops); err != nil {
+		return err
+	}
+	for _, op := range ops {
+		switch op.Kind {
+		case OpPut:
+			s.mirrorPut(op.Key)
+		case OpRemove:
+			for _, shadow := range s.shadows {
+				_ = shadow.Remove(op.Key)
+			}
+		case OpClear:
+			for _, shadow := range s.shadows {
+				shadow.Clear()
+			}
+		}
+	}
+	return nil
+}
+
+func (s *shadowedImpl[K, V]) Remove(key K) error {
+	for _, shadow := range s.shadows {
+		_ = shadow.Remove(key)
+	}
+	return s.Replica.Remove(key)
+}
+
+func (s *shadowedImpl[K, V]) Clear() {
+	s.Replica.Clear()
+	for _, shadow := range s.shadows {
+		shadow.Clear()
+	}
+}
+
+func (s *shadowedImpl[K, V]) WarmFrom(src WarmSource[K, V], limit int) (int, error) {
+	copied, err := s.Replica.WarmFrom(src, limit)
+	if err != nil {
+		return copied, err
+	}
+	for _, shadow := range s.shadows {
+		_, _ = shadow.WarmFrom(keysOf[K, V]{src}, limit)
+	}
+	return copied, nil
+}
+
+func (s *shadowedImpl[K, V]) SyncFrom(src SyncSource[K, V], since uint64) (uint64, error) {
+	if src == nil {
+		return s.Replica.SyncFrom(nil, since)
+	}
+	// the shadows replay what the primary read from src instead of reading it again
+	recorded := &recordingSource[K, V]{SyncSource: src, entries: make(map[K]Entry[K, V])}
+	var wrapped SyncSource[K, V] = recorded
+	if warm, ok := src.(WarmSource[K, V]); ok {
+		wrapped = recordingWarmSource[K, V]{recorded, warm}
+	}
+	gen, err := s.Replica.SyncFrom(wrapped, since)
+	if err != nil {
+		return gen, err
+	}
+	if errors.Is(recorded.err, ErrHistoryTruncated) {
+		// the primary holds the full copy of src now
+		for _, shadow := range s.shadows {
+			shadow.Clear()
+			_, _ = shadow.WarmFrom(keysOf[K, V]{s.Replica}, 0)
+		}
+		return gen, nil
+	}
+	// updated keys are put with the frequencies they have in src, like the primary does
+	updated := keyFrequencies[K]{freqs: make(map[K]int)}
+	for i := len(recorded.changes) - 1; i >= 0; i-- {
+		key := recorded.changes[i].Key
+		if entry, ok := recorded.entries[key]; !recorded.changes[i].Removed && ok {
+			updated.keys = append(updated.keys, key)
+			updated.freqs[key] = entry.Frequency
+		}
+	}
+	for _, shadow := range s.shadows {
+		for _, change := range recorded.changes {
+			_ = shadow.Remove(change.Key)
+		}
+		if len(updated.keys) > 0 {
+			_, _ = shadow.WarmFrom(updated, len(updated.keys))
+		}
+	}
+	return gen, nil
+}
+
+// recordingSource passes the reads of SyncFrom to src and records the changes and the entries read
+type recordingSource[K comparable, V any] struct {
+	SyncSource[K, V]
+	changes []Change[K]
+	err     error
+	entries map[K]Entry[K, V]
+}
+
+func (r *recordingSource[K, V]) ChangesSince(gen uint64) ([]Change[K], error) {
+	r.changes, r.err = r.SyncSource.ChangesSince(gen)
+	return r.changes, r.err
+}
+
+func (r *recordingSource[K, V]) EntryInfo(key K) (Entry[K, V], error) {
+	entry, err := r.SyncSource.EntryInfo(key)
+	if err == nil {
+		r.entries[key] = entry
+	}
+	return entry, err
+}
+
+// recordingWarmSource is a recordingSource of a src implementing WarmSource too,
+// so the primary can still warm from it if the history of src is truncated
+type recordingWarmSource[K comparable, V any] struct {
+	*recordingSource[K, V]
+	WarmSource[K, V]
+}
+
+// keysOf is a WarmSource of the keys of src, used to warm the shadows
+type keysOf[K comparable, V any] struct {
+	src WarmSource[K, V]
+}
+
+func (k keysOf[K, V]) All() iter.Seq2[K, struct{}] {
+	return func(yield func(K, struct{}) bool) {
+		for key := range k.src.All() {
+			if !yield(key, struct{}{}) {
+				return
+			}
+		}
+	}
+}
+
+func (k keysOf[K, V]) GetKeyFrequency(key K) (int, error) {
+	return k.src.GetKeyFrequency(key)
+}
+
+// keyFrequencies is a WarmSource of keys with the given frequencies, in the order of keys
+type keyFrequencies[K comparable] struct {
+	keys  []K
+	freqs map[K]int
+}
+
+func (k keyFrequencies[K]) All() iter.Seq2[K, struct{}] {
+	return func(yield func(K, struct{}) bool) {
+		for _, key := range k.keys {
+			if !yield(key, struct{}{}) {
+				return
+			}
+		}
+	}
+}
+
+func (k keyFrequencies[K]) GetKeyFrequency(key K) (int, error) {
+	freq, ok := k.freqs[key]
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+	return freq, nil
+}
@@ -0,0 +1,111 @@
+package lfu
+
+import (
+	"context"
+	"sync"
+	"time"
+	"weak"
+)
+
+// WithSoftLimit sets the soft limit of the cache size, the capacity being the hard limit.
+// Put evicts only when the cache reaches the capacity, so bursts above the soft limit
+// do not add eviction latency to the request path. Entries above the soft limit are evicted
+// off the request path: by the background trimming of WithBackgroundTrim or TrimInBackground, or by Trim.
+// Returns ErrInvalidOption if limit is not positive or exceeds the capacity.
+func WithSoftLimit[K comparable, V any](limit int) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if limit < 1 || limit > c.capacity {
+			return ErrInvalidOption
+		}
+		c.extend().softLimit = limit
+		return nil
+	}
+}
+
+// WithBackgroundTrim makes a goroutine evict the entries above the soft limit set by WithSoftLimit
+// every interval. The cache is not thread-safe, so the goroutine holds locker, which has to guard every
+// other access to the cache, while trimming. The goroutine stops when the cache is garbage collected.
+// Returns ErrInvalidOption if interval is not positive or locker is nil, or, from NewWithOptions,
+// if WithSoftLimit is missing.
+func WithBackgroundTrim[K comparable, V any](interval time.Duration, locker sync.Locker) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if interval <= 0 || locker == nil {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		x.trimInterval = interval
+		x.trimLocker = locker
+		return nil
+	}
+}
+
+// Trim evicts the least frequently used entries above the soft limit set by WithSoftLimit
+// and returns the number of evicted entries.
+//
+// O(number of evicted entries)
+func (l *cacheImpl[K, V]) Trim() int {
+	trimmed := 0
+	if l.ext == nil {
+		return 0
+	}
+	for l.ext.softLimit > 0 && l.elemList.Size() > l.ext.softLimit {
+		l.evict()
+		trimmed++
+	}
+	return trimmed
+}
+
+// startTrimming starts the goroutine of WithBackgroundTrim. It refers to the cache weakly,
+// so it does not keep the cache reachable
+func (l *cacheImpl[K, V]) startTrimming() {
+	cache := weak.Make(l)
+	interval, locker, done := l.ext.trimInterval, l.ext.trimLocker, make(chan struct{})
+	l.ext.trimDone = done
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			l := cache.Value()
+			if l == nil {
+				return
+			}
+			locker.Lock()
+			l.Trim()
+			locker.Unlock()
+		}
+	}()
+}
+
+// Trimmer is implemented by caches evicting the entries above a soft limit, like the caches of NewWithOptions
+type Trimmer interface {
+	Trim() int
+}
+
+// TrimInBackground works like WithBackgroundTrim for a cache created without it, e.g. wrapped by NewSynchronized,
+// which is then passed as locker, but the goroutine calling Trim every interval stops when ctx is done.
+// Returns ErrInvalidOption if cache or locker is nil or interval is not positive.
+func TrimInBackground(ctx context.Context, cache Trimmer, locker sync.Locker, interval time.Duration) error {
+	if cache == nil || locker == nil || interval <= 0 {
+		return ErrInvalidOption
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				locker.Lock()
+				cache.Trim()
+				locker.Unlock()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,73 @@
+package lfu
+
+// Stats is a snapshot of the cache statistics
+type Stats struct {
+	// Saturations is the number of frequency increments skipped because the frequency reached its maximum
+	Saturations uint64
+	// SuppressedIncrements is the number of frequency increments skipped by the rate limit of WithIncrementInterval
+	SuppressedIncrements uint64
+	// Reclaims is the number of borrowed entries evicted to return the quota to its namespace
+	Reclaims uint64
+	// Rejections is the number of values not cached because of their weight
+	Rejections uint64
+	// CallbackPanics is the number of panics recovered from the callbacks, the loader and the other user functions
+	CallbackPanics uint64
+
+	// Blocks is the number of distinct frequencies in the cache
+	Blocks int
+	// LowestFrequency is the lowest frequency in the cache and LowestBlockSize is the number of its entries.
+	// A large block of frequency 1 is the signature of scan pollution
+	LowestFrequency, LowestBlockSize int
+	// HighestFrequency is the highest frequency in the cache and HighestBlockSize is the number of its entries
+	HighestFrequency, HighestBlockSize int
+	// Borrowed is the number of entries of namespaces over their quotas
+	Borrowed int
+	// InternedValues is the number of distinct values shared by the entries if values are interned
+	InternedValues int
+
+	// Hits counts the hits and misses of the wrapper of NewShadowed and ShadowHits the ones of its shadows
+	// by their names, so their hit rates can be compared. They are zero and nil for the other caches
+	Hits       HitStats
+	ShadowHits map[string]HitStats
+}
+
+// statEvent is an event counted in Stats
+type statEvent int
+
+const (
+	statSaturation statEvent = iota
+	statSuppressedIncrement
+	statReclaim
+	statRejection
+	statCallbackPanic
+	statEvents
+)
+
+// countingStats counts the events of the cache, it is nil if the statistics are disabled by WithoutStats
+type countingStats [statEvents]uint64
+
+// record counts the event unless the statistics are disabled
+func (l *cacheImpl[K, V]) record(event statEvent) {
+	if l.stats != nil {
+		l.stats[event]++
+	}
+}
+
+func (c *countingStats) counters(stats *Stats) {
+	stats.Saturations = c[statSaturation]
+	stats.SuppressedIncrements = c[statSuppressedIncrement]
+	stats.Reclaims = c[statReclaim]
+	stats.Rejections = c[statRejection]
+	stats.CallbackPanics = c[statCallbackPanic]
+}
+
+// WithoutStats disables counting of the events in Stats, which then reports zero counters.
+// The state of the cache, e.g. Blocks or LowestFrequency, is still reported.
+// Options adding hooks to Get and Put, e.g. WithRecorder or WithStatsReporter, are not affected:
+// a cache without them does not check any hook on Get and Put.
+func WithoutStats[K comparable, V any]() Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		c.stats = nil
+		return nil
+	}
+}
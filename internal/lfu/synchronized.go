@@ -0,0 +1,265 @@
+package lfu
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync/atomic"
+	"time"
+)
+
+// WaitStats describes waiting for the lock of the synchronized cache
+type WaitStats struct {
+	// Acquisitions is the number of times the lock was acquired
+	Acquisitions uint64
+	// Abandoned is the number of operations abandoned because their context was done while waiting
+	Abandoned uint64
+	// Waited is the total time spent waiting for the lock
+	Waited time.Duration
+}
+
+// synchronizedImpl guards the cache with a lock, which can be waited for with a context
+type synchronizedImpl[K comparable, V any] struct {
+	cache        Cache[K, V]
+	lock         chan struct{}
+	acquisitions atomic.Uint64
+	abandoned    atomic.Uint64
+	waited       atomic.Int64
+}
+
+// splitLoader is implemented by the caches whose loader can be called without holding the lock
+type splitLoader[K comparable, V any] interface {
+	startLoad() (BulkLoader[K, V], error)
+	finishLoad(keys []K, loaded map[K]V, err error) (map[K]V, error)
+}
+
+// NewSynchronized wraps the cache, so it can be used by many goroutines.
+// The cache must not be used directly afterward, except under Lock.
+// Returns ErrInvalidOption if cache is nil.
+func NewSynchronized[K comparable, V any](cache Cache[K, V]) (*synchronizedImpl[K, V], error) {
+	if cache == nil {
+		return nil, ErrInvalidOption
+	}
+	return &synchronizedImpl[K, V]{cache: cache, lock: make(chan struct{}, 1)}, nil
+}
+
+// acquire waits for the lock until ctx is done and returns the error of ctx if it is done first
+func (s *synchronizedImpl[K, V]) acquire(ctx context.Context) error {
+	select {
+	case s.lock <- struct{}{}:
+		s.acquisitions.Add(1)
+		return nil
+	default:
+	}
+	start := time.Now()
+	defer func() {
+		s.waited.Add(int64(time.Since(start)))
+	}()
+	select {
+	case s.lock <- struct{}{}:
+		s.acquisitions.Add(1)
+		return nil
+	case <-ctx.Done():
+		s.abandoned.Add(1)
+		return ctx.Err()
+	}
+}
+
+// Lock acquires the lock, so the wrapped cache can be used directly, e.g. by TrimInBackground
+func (s *synchronizedImpl[K, V]) Lock() {
+	_ = s.acquire(context.Background())
+}
+
+// Unlock releases the lock acquired by Lock
+func (s *synchronizedImpl[K, V]) Unlock() {
+	<-s.lock
+}
+
+// Get works like Cache.Get
+func (s *synchronizedImpl[K, V]) Get(key K) (V, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Get(key)
+}
+
+// Put works like Cache.Put
+func (s *synchronizedImpl[K, V]) Put(key K, value V) {
+	s.Lock()
+	defer s.Unlock()
+	s.cache.Put(key, value)
+}
+
+// GetMulti works like Cache.GetMulti
+func (s *synchronizedImpl[K, V]) GetMulti(keys []K) map[K]V {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.GetMulti(keys)
+}
+
+// PutMulti works like Cache.PutMulti
+func (s *synchronizedImpl[K, V]) PutMulti(entries map[K]V) {
+	s.Lock()
+	defer s.Unlock()
+	s.cache.PutMulti(entries)
+}
+
+// Apply works like Cache.Apply
+func (s *synchronizedImpl[K, V]) Apply(ops []Op[K, V]) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Apply(ops)
+}
+
+// Remove works like Cache.Remove
+func (s *synchronizedImpl[K, V]) Remove(key K) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Remove(key)
+}
+
+// PutWithEvicted works like Cache.PutWithEvicted
+func (s *synchronizedImpl[K, V]) PutWithEvicted(key K, value V) (K, V, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.PutWithEvicted(key, value)
+}
+
+// PutTransient works like Cache.PutTransient
+func (s *synchronizedImpl[K, V]) PutTransient(key K, value V) {
+	s.Lock()
+	defer s.Unlock()
+	s.cache.PutTransient(key, value)
+}
+
+// All works like Cache.All, but iterates over a copy of the entries taken under the lock,
+// so the loop body can use the cache
+func (s *synchronizedImpl[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.Lock()
+		var entries []Entry[K, V]
+		for key, value := range s.cache.All() {
+			entries = append(entries, Entry[K, V]{Key: key, Value: value})
+		}
+		s.Unlock()
+		for _, entry := range entries {
+			if !yield(entry.Key, entry.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Size works like Cache.Size
+func (s *synchronizedImpl[K, V]) Size() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Size()
+}
+
+// Capacity works like Cache.Capacity
+func (s *synchronizedImpl[K, V]) Capacity() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Capacity()
+}
+
+// Clear works like Cache.Clear
+func (s *synchronizedImpl[K, V]) Clear() {
+	s.Lock()
+	defer s.Unlock()
+	s.cache.Clear()
+}
+
+// GetKeyFrequency works like Cache.GetKeyFrequency
+func (s *synchronizedImpl[K, V]) GetKeyFrequency(key K) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.GetKeyFrequency(key)
+}
+
+// Stats works like Cache.Stats
+func (s *synchronizedImpl[K, V]) Stats() Stats {
+	s.Lock()
+	defer s.Unlock()
+	return s.cache.Stats()
+}
+
+// GetCtx works like Get, but loads a missing key with the BulkLoader of the cache, if it has one,
+// and returns the error of ctx if ctx is done while waiting for the lock.
+// The loader of a cache created by New or NewWithOptions is called with ctx without holding the lock,
+// so other goroutines can use the cache meanwhile. If the key is put by them during the load,
+// their value is returned. Loaders of other caches are called by LoadMulti under the lock.
+func (s *synchronizedImpl[K, V]) GetCtx(ctx context.Context, key K) (V, error) {
+	if err := s.acquire(ctx); err != nil {
+		var zero V
+		return zero, err
+	}
+	value, err := s.cache.Get(key)
+	if err == nil {
+		s.Unlock()
+		return value, nil
+	}
+	split, ok := s.cache.(splitLoader[K, V])
+	if !ok {
+		defer s.Unlock()
+		return s.loadLocked(ctx, key, value, err)
+	}
+	loader, loadErr := split.startLoad()
+	s.Unlock()
+	if errors.Is(loadErr, ErrNoLoader) {
+		return value, err
+	}
+	if loadErr != nil {
+		return value, loadErr
+	}
+
+	keys := []K{key}
+	loaded, loadErr := load(ctx, loader, keys)
+	// the result is recorded even if ctx is done meanwhile, so the circuit breaker sees every call
+	s.Lock()
+	values, loadErr := split.finishLoad(keys, loaded, loadErr)
+	s.Unlock()
+	if loadErr != nil {
+		return value, loadErr
+	}
+	if loaded, ok := values[key]; ok {
+		return loaded, nil
+	}
+	return value, err
+}
+
+// loadLocked loads the key with LoadMulti of the cache, which is called holding the lock
+func (s *synchronizedImpl[K, V]) loadLocked(ctx context.Context, key K, value V, err error) (V, error) {
+	loader, ok := s.cache.(MultiLoader[K, V])
+	if !ok {
+		return value, err
+	}
+	values, loadErr := loader.LoadMulti(ctx, []K{key})
+	if loadErr != nil && !errors.Is(loadErr, ErrNoLoader) {
+		return value, loadErr
+	}
+	if loaded, ok := values[key]; ok {
+		return loaded, nil
+	}
+	return value, err
+}
+
+// PutCtx works like Put, but returns the error of ctx without putting the value
+// if ctx is done while waiting for the lock
+func (s *synchronizedImpl[K, V]) PutCtx(ctx context.Context, key K, value V) error {
+	if err := s.acquire(ctx); err != nil {
+		return err
+	}
+	defer s.Unlock()
+	s.cache.Put(key, value)
+	return nil
+}
+
+// WaitStats returns a snapshot of the statistics of waiting for the lock
+func (s *synchronizedImpl[K, V]) WaitStats() WaitStats {
+	return WaitStats{
+		Acquisitions: s.acquisitions.Load(),
+		Abandoned:    s.abandoned.Load(),
+		Waited:       time.Duration(s.waited.Load()),
+	}
+}
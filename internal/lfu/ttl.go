@@ -0,0 +1,70 @@
+package lfu
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNoTTL = errors.New("entries have no TTL")
+
+// WithTTL makes every entry expire ttl after it was inserted or its value was last put.
+// Expired entries are removed lazily: by the first Get, Put, TTL or GetWithTTL of their keys,
+// with ReasonExpired passed to the eviction callbacks. Until then they take space in the cache
+// and are evicted like the other entries. Returns ErrInvalidOption if ttl is not positive.
+func WithTTL[K comparable, V any](ttl time.Duration) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if ttl <= 0 {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		x.ttl = ttl.Nanoseconds()
+		x.hooked = true
+		return nil
+	}
+}
+
+// expire removes the entry of the key if it has expired by now and reports whether the key is cached
+func (l *cacheImpl[K, V]) expire(key K, now int64) bool {
+	link, ok := l.keyToElement[key]
+	if !ok {
+		return false
+	}
+	if l.ext.states[key].expires > now {
+		return true
+	}
+	l.remove(link, ReasonExpired)
+	if l.ext.replicate != nil {
+		l.replicateOp(Op[K, V]{Kind: OpRemove, Key: key})
+	}
+	return false
+}
+
+// TTL returns the remaining lifetime of the entry if the key exists in the cache and has not expired,
+// otherwise, returns ErrKeyNotFound. Returns ErrNoTTL unless WithTTL is set.
+//
+// O(1)
+func (l *cacheImpl[K, V]) TTL(key K) (time.Duration, error) {
+	if l.ext == nil || l.ext.ttl == 0 {
+		return 0, ErrNoTTL
+	}
+	now := l.clock()
+	if !l.expire(key, now) {
+		return 0, ErrKeyNotFound
+	}
+	return time.Duration(l.ext.states[key].expires - now), nil
+}
+
+// GetWithTTL works like Get and additionally returns the remaining lifetime of the entry,
+// so it does not need a separate TTL call. Returns ErrNoTTL unless WithTTL is set.
+//
+// O(1)
+func (l *cacheImpl[K, V]) GetWithTTL(key K) (V, time.Duration, error) {
+	if l.ext == nil || l.ext.ttl == 0 {
+		return l.defaultValue, 0, ErrNoTTL
+	}
+	value, err := l.Get(key)
+	if err != nil {
+		return value, 0, err
+	}
+	return value, time.Duration(l.ext.states[key].expires - l.clock()), nil
+}
@@ -0,0 +1,239 @@
+package lfu
+
+import (
+	"cmp"
+	"container/heap"
+	"errors"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+var ErrNilSource = errors.New("source is nil")
+
+// WarmSource is the part of a cache WarmFrom copies the entries from,
+// so it can be implemented by any cache, e.g. a client of a remote one.
+// All may yield the entries in any order, the hottest ones are selected by GetKeyFrequency
+type WarmSource[K comparable, V any] interface {
+	All() iter.Seq2[K, V]
+	GetKeyFrequency(key K) (int, error)
+}
+
+// SyncSource is the part of a cache SyncFrom reads the changes from, so it can be implemented
+// by any cache, e.g. a client of a remote one. A source also implementing WarmSource can be
+// copied in full when its history of changes is truncated
+type SyncSource[K comparable, V any] interface {
+	Generation() uint64
+	ChangesSince(gen uint64) ([]Change[K], error)
+	EntryInfo(key K) (Entry[K, V], error)
+}
+
+func (l *cacheImpl[K, V]) WarmFrom(src WarmSource[K, V], limit int) (int, error) {
+	if src == nil {
+		return 0, ErrNilSource
+	}
+	if limit <= 0 || limit > l.capacity {
+		limit = l.capacity
+	}
+	entries := hottest(src, limit)
+
+	copied := 0
+	l.begin()
+	for i := len(entries) - 1; i >= 0; i-- {
+		if l.insertWithFreq(entries[i].key, entries[i].value, entries[i].freq) {
+			copied++
+		}
+	}
+	l.end()
+	return copied, nil
+}
+
+// warmEntry is an entry of a WarmSource with its frequency and its position in the order of All
+type warmEntry[K comparable, V any] struct {
+	key   K
+	value V
+	freq  int
+	order int
+}
+
+// compareWarm orders entries from the hottest: by descending frequency, then in the order of All
+func compareWarm[K comparable, V any](a, b warmEntry[K, V]) int {
+	return cmp.Or(cmp.Compare(b.freq, a.freq), cmp.Compare(a.order, b.order))
+}
+
+// warmHeap keeps the hottest entries seen so far with the coldest one on top
+type warmHeap[K comparable, V any] []warmEntry[K, V]
+
+func (h warmHeap[K, V]) Len() int           { return len(h) }
+func (h warmHeap[K, V]) Less(i, j int) bool { return compareWarm(h[i], h[j]) > 0 }
+func (h warmHeap[K, V]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *warmHeap[K, V]) Push(x any)        { *h = append(*h, x.(warmEntry[K, V])) }
+
+func (h *warmHeap[K, V]) Pop() any {
+	last := (*h)[len(*h)-1]
+	*h = (*h)[:len(*h)-1]
+	return last
+}
+
+// hottest returns up to limit entries of src with the highest frequencies from the hottest,
+// entries with the same frequency in the order of All. Entries whose frequency is unknown are skipped.
+// O(size of src * log(limit))
+func hottest[K comparable, V any](src WarmSource[K, V], limit int) []warmEntry[K, V] {
+	entries := make(warmHeap[K, V], 0, limit)
+	order := 0
+	for key, value := range src.All() {
+		freq, err := src.GetKeyFrequency(key)
+		if err != nil {
+			continue
+		}
+		entry := warmEntry[K, V]{key: key, value: value, freq: freq, order: order}
+		order++
+		if len(entries) < limit {
+			heap.Push(&entries, entry)
+		} else if compareWarm(entry, entries[0]) < 0 {
+			entries[0] = entry
+			heap.Fix(&entries, 0)
+		}
+	}
+	slices.SortFunc(entries, compareWarm)
+	return entries
+}
+
+// insertWithFreq puts the key with the given frequency as the most recently used element of its block.
+// Frequency 0 is kept for transient elements, other frequencies are at least 1.
+// An existing key is replaced. If making room for the key, by number or by weight, would evict an element
+// with a higher frequency, the key is not inserted and false is returned. The change is streamed to the replication sink
+func (l *cacheImpl[K, V]) insertWithFreq(key K, value V, freq int) bool {
+	_, existed := l.keyToElement[key]
+	inserted := l.placeWithFreq(key, value, freq)
+	if l.ext != nil && l.ext.replicate != nil {
+		if inserted {
+			l.replicateOp(Op[K, V]{Kind: OpPut, Key: key, Value: value})
+		} else if existed {
+			l.replicateOp(Op[K, V]{Kind: OpRemove, Key: key})
+		}
+	}
+	return inserted
+}
+
+// placeWithFreq works like insertWithFreq, but does not stream the change
+func (l *cacheImpl[K, V]) placeWithFreq(key K, value V, freq int) bool {
+	x := l.ext
+	if freq != 0 {
+		freq = max(1, min(freq, l.maxFreq))
+	}
+	hits := freq
+	if x != nil && x.logFrequency && freq > 0 && freq < l.maxFreq {
+		freq = 1 << (bits.Len(uint(freq)) - 1)
+	}
+	var weight int64
+	var err error
+	weighed := x != nil && x.weigher != nil
+	if weighed {
+		weight, err = l.weigh(key, value)
+	}
+	if link, ok := l.keyToElement[key]; ok {
+		l.remove(link, ReasonReplaced)
+	}
+	if l.capacity == 0 || err != nil || (weighed && (l.tooHeavy(key, weight) || !l.weightFits(key, weight, freq))) {
+		return false
+	}
+	if link := l.namespaceVictim(key); link != nil {
+		if link.Value.freq > freq {
+			return false
+		}
+		l.evictForNamespace(link, key)
+	} else if l.elemList.Size() == l.capacity {
+		if l.elemList.Back().Value.freq > freq {
+			return false
+		}
+		l.evictLink(l.victim())
+	}
+	if weighed {
+		if x.namespaces != nil {
+			l.evictNamespaceWeight(key, weight, nil)
+		}
+		l.evictWeight(weight, nil)
+	}
+
+	at, ok := l.freqToStart[freq]
+	if ok {
+		l.freqToCount[freq]++
+	} else {
+		at = l.elemList.Head()
+		lower := -1
+		for f := range l.freqToStart {
+			if f < freq && f > lower {
+				lower = f
+			}
+		}
+		if lower >= 0 {
+			at = l.freqToStart[lower]
+		}
+		l.freqToCount[freq] = 1
+	}
+	elem := &element[K, V]{key: key, value: value, freq: freq}
+	if x != nil {
+		if x.interned != nil {
+			elem.value = x.interned.intern(value)
+		}
+		if x.states != nil {
+			l.addState(key, hits, weight)
+		}
+	}
+	link := l.elemList.InsertBefore(elem, at)
+	l.freqToStart[freq] = link
+	l.keyToElement[key] = link
+	l.weight += weight
+	if x != nil {
+		if x.namespaces != nil {
+			x.namespaces.add(key, 1, weight)
+		}
+		if x.indexes != nil {
+			l.indexed(key, elem.value, true)
+		}
+	}
+	l.changed(key, false)
+	return true
+}
+
+func (l *cacheImpl[K, V]) SyncFrom(src SyncSource[K, V], since uint64) (uint64, error) {
+	if src == nil {
+		return since, ErrNilSource
+	}
+	gen := src.Generation()
+	changes, err := src.ChangesSince(since)
+	if warm, ok := src.(WarmSource[K, V]); ok && errors.Is(err, ErrHistoryTruncated) {
+		l.Clear()
+		_, _ = l.WarmFrom(warm, 0)
+		return gen, nil
+	}
+	if err != nil {
+		return since, err
+	}
+	l.begin()
+	for _, change := range changes {
+		entry, err := src.EntryInfo(change.Key)
+		if change.Removed || err != nil {
+			if link, ok := l.keyToElement[change.Key]; ok {
+				l.remove(link, ReasonRemoved)
+				if l.ext != nil && l.ext.replicate != nil {
+					l.replicateOp(Op[K, V]{Kind: OpRemove, Key: change.Key})
+				}
+			}
+			continue
+		}
+		l.insertWithFreq(entry.Key, entry.Value, entry.Frequency)
+	}
+	l.end()
+	return gen, nil
+}
+
+func (l *cacheImpl[K, V]) PutTransient(key K, value V) {
+	if l.ext != nil && l.ext.recorder != nil {
+		l.ext.recorder.record(RecordPut, key)
+	}
+	l.begin()
+	l.insertWithFreq(key, value, 0)
+	l.end()
+}
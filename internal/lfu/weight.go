@@ -0,0 +1,127 @@
+package lfu
+
+import (
+	"errors"
+	"lfucache/internal/linkedlist"
+)
+
+var ErrValueTooHeavy = errors.New("value is too heavy")
+
+// WithMaxWeight limits the total weight of the entries in addition to their number.
+// weigher returns the weight of an entry, negative weights are treated as zero.
+// Put evicts as many of the least frequently used entries as needed in one pass, and entries
+// heavier than maxWeight are not cached at all. Ties are always broken by recency.
+// Returns ErrInvalidOption if maxWeight is not positive or weigher is nil.
+func WithMaxWeight[K comparable, V any](maxWeight int64, weigher func(K, V) int64) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if maxWeight < 1 || weigher == nil {
+			return ErrInvalidOption
+		}
+		x := c.extend()
+		x.maxWeight = maxWeight
+		x.weigher = weigher
+		return nil
+	}
+}
+
+// WithMaxValueWeight rejects values heavier than maxValueWeight, so a single huge value cannot evict
+// a large part of the cache. Rejected values are not cached, the previous value of the key is removed,
+// and rejections are counted in Stats.Rejections and reported by TryPut.
+// It needs the weigher set by WithMaxWeight. Returns ErrInvalidOption if maxValueWeight is not positive,
+// or, from NewWithOptions, if WithMaxWeight is missing.
+func WithMaxValueWeight[K comparable, V any](maxValueWeight int64) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if maxValueWeight < 1 {
+			return ErrInvalidOption
+		}
+		c.extend().maxValueWeight = maxValueWeight
+		return nil
+	}
+}
+
+// weigh returns the weight of the entry, or an error wrapping ErrCallbackPanic if the weigher panics
+func (l *cacheImpl[K, V]) weigh(key K, value V) (int64, error) {
+	weight, err := guarded(l.reportPanic, "weigher", func() int64 { return l.ext.weigher(key, value) })
+	return max(0, weight), err
+}
+
+// WithOnEvictBatch sets the callback invoked once per Put that evicts entries to make room for the new one,
+// with all of them in eviction order, or once per Apply with the entries evicted by all its operations. It is invoked after the per-entry callbacks
+// and the slice is not used by the cache afterward. Returns ErrInvalidOption if onEvict is nil.
+func WithOnEvictBatch[K comparable, V any](onEvict func([]Entry[K, V])) Option[K, V] {
+	return func(c *cacheImpl[K, V]) error {
+		if onEvict == nil {
+			return ErrInvalidOption
+		}
+		c.extend().onEvictBatch = onEvict
+		return nil
+	}
+}
+
+// tooHeavy reports whether a value of the key of the given weight is rejected
+func (l *cacheImpl[K, V]) tooHeavy(key K, weight int64) bool {
+	return weight > l.ext.maxWeight || (l.ext.maxValueWeight > 0 && weight > l.ext.maxValueWeight) ||
+		(l.ext.namespaces != nil && l.ext.namespaces.tooHeavy(key, weight))
+}
+
+// evictLink evicts the element to make room for another one and returns it
+func (l *cacheImpl[K, V]) evictLink(link *linkedlist.Node[*element[K, V]]) *element[K, V] {
+	elem := link.Value
+	if l.ext != nil && l.ext.onEvictBatch != nil {
+		l.ext.evicted = append(l.ext.evicted, l.entry(elem))
+	}
+	l.remove(link, ReasonEvicted)
+	return elem
+}
+
+// evictWeight evicts the least frequently used elements except keep until weight more fits in the cache
+// and returns the first evicted element, or nil
+func (l *cacheImpl[K, V]) evictWeight(weight int64, keep *linkedlist.Node[*element[K, V]]) *element[K, V] {
+	var first *element[K, V]
+	for link := l.elemList.Back(); l.weight+weight > l.ext.maxWeight; {
+		prev := link.Prev()
+		if link != keep {
+			if elem := l.evictLink(link); first == nil {
+				first = elem
+			}
+		}
+		link = prev
+	}
+	return first
+}
+
+// weightFits reports whether weight more of the key fits in the cache and in the max weight of its namespace
+// by evicting only elements used at most freq times, which are the ones at the back of the list
+func (l *cacheImpl[K, V]) weightFits(key K, weight int64, freq int) bool {
+	x := l.ext
+	total := l.weight + weight
+	var ns string
+	var nsTotal, nsMax int64
+	limited := false
+	if x.namespaces != nil {
+		ns = x.namespaces.of(key)
+		nsMax, limited = x.namespaces.maxWeights[ns]
+		nsTotal = x.namespaces.weights[ns] + weight
+	}
+	for link := l.elemList.Back(); total > x.maxWeight || (limited && nsTotal > nsMax); link = link.Prev() {
+		if link == l.elemList.Head() || link.Value.freq > freq {
+			return false
+		}
+		weight := x.states[link.Value.key].weight
+		total -= weight
+		if limited && x.namespaces.of(link.Value.key) == ns {
+			nsTotal -= weight
+		}
+	}
+	return true
+}
+
+// flushEvicted passes the entries evicted by the last Put to the batch callback
+func (l *cacheImpl[K, V]) flushEvicted() {
+	if len(l.ext.evicted) == 0 {
+		return
+	}
+	evicted := l.ext.evicted
+	l.ext.evicted = nil
+	l.safely("batch eviction callback", func() { l.ext.onEvictBatch(evicted) })
+}
@@ -0,0 +1,88 @@
+package lfutest
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math/rand/v2"
+)
+
+var ErrMismatch = errors.New("cache diverged from the reference model")
+
+// Config describes a randomized operation sequence
+type Config struct {
+	// Operations is the number of random operations, 1000 if zero
+	Operations int
+	// Keys is the number of distinct keys used, 2*capacity+1 if zero
+	Keys int
+	// Seed makes the sequence reproducible
+	Seed uint64
+}
+
+// Check applies a random sequence of Put, Get and GetKeyFrequency operations to an empty cache
+// of the given capacity and to the reference model, comparing every result, the size and the iteration order.
+// Errors of missing keys are compared with errors.Is, see ErrKeyNotFound.
+// Returns an error wrapping ErrMismatch describing the first divergence.
+func Check(cache Cache[int, int], capacity int, cfg Config) error {
+	if cfg.Operations == 0 {
+		cfg.Operations = 1000
+	}
+	if cfg.Keys == 0 {
+		cfg.Keys = 2*capacity + 1
+	}
+	rnd := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))
+	model := NewModel[int, int](capacity)
+
+	for op := range cfg.Operations {
+		key := rnd.IntN(cfg.Keys)
+		var (
+			name          string
+			got, expected string
+		)
+		switch rnd.IntN(3) {
+		case 0:
+			value := rnd.Int()
+			name = fmt.Sprintf("Put(%d, %d)", key, value)
+			cache.Put(key, value)
+			model.Put(key, value)
+		case 1:
+			name = fmt.Sprintf("Get(%d)", key)
+			got, expected = result(cache.Get(key)), result(model.Get(key))
+		default:
+			name = fmt.Sprintf("GetKeyFrequency(%d)", key)
+			got, expected = result(cache.GetKeyFrequency(key)), result(model.GetKeyFrequency(key))
+		}
+		if got == expected {
+			got, expected = fmt.Sprint(cache.Size()), fmt.Sprint(model.Size())
+			name += ", then Size()"
+		}
+		if got == expected {
+			got, expected = entries(cache.All()), entries(model.All())
+			name += ", then All()"
+		}
+		if got != expected {
+			return fmt.Errorf("%w: operation %d %s with seed %d: got %s, expected %s",
+				ErrMismatch, op, name, cfg.Seed, got, expected)
+		}
+	}
+	return nil
+}
+
+// result formats the outcome of an operation, errors wrapping ErrKeyNotFound are formatted alike
+func result[T any](value T, err error) string {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		return "ErrKeyNotFound"
+	case err != nil:
+		return "error " + err.Error()
+	}
+	return fmt.Sprint(value)
+}
+
+func entries[K comparable, V any](all iter.Seq2[K, V]) string {
+	pairs := make([]string, 0)
+	for k, v := range all {
+		pairs = append(pairs, fmt.Sprintf("%v:%v", k, v))
+	}
+	return fmt.Sprint(pairs)
+}
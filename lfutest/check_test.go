@@ -0,0 +1,55 @@
+package lfutest
+
+import (
+	"fmt"
+	"lfucache/internal/lfu"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheMatchesModel(t *testing.T) {
+	t.Parallel()
+
+	for capacity := range 8 {
+		for seed := range uint64(5) {
+			err := Check(lfu.New[int, int](capacity), capacity, Config{Seed: seed})
+			require.NoError(t, err)
+		}
+	}
+}
+
+type forgetful struct {
+	*Model[int, int]
+}
+
+func (f forgetful) Put(key, value int) {
+	if key%7 != 3 {
+		f.Model.Put(key, value)
+	}
+}
+
+func TestCheckDetectsMismatch(t *testing.T) {
+	t.Parallel()
+
+	err := Check(forgetful{NewModel[int, int](4)}, 4, Config{Keys: 20, Seed: 1})
+	require.ErrorIs(t, err, ErrMismatch)
+}
+
+type wrapping struct {
+	*Model[int, int]
+}
+
+func (w wrapping) Get(key int) (int, error) {
+	value, err := w.Model.Get(key)
+	if err != nil {
+		return 0, fmt.Errorf("get %d: %w", key, err)
+	}
+	return value, nil
+}
+
+func TestCheckComparesErrorsByIdentity(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, Check(wrapping{NewModel[int, int](4)}, 4, Config{Keys: 20, Seed: 1}))
+}
@@ -0,0 +1,122 @@
+// Package lfutest provides a reference LFU model and a conformance harness
+// running randomized operation sequences against cache implementations.
+package lfutest
+
+import (
+	"cmp"
+	"iter"
+	"lfucache/internal/lfu"
+	"slices"
+)
+
+// ErrKeyNotFound is returned by the model for missing keys. Get and GetKeyFrequency of checked caches
+// must return an error wrapping it for missing keys, lfu caches return it as is
+var ErrKeyNotFound = lfu.ErrKeyNotFound
+
+// Cache is the part of lfu.Cache checked by the harness
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, error)
+	Put(key K, value V)
+	All() iter.Seq2[K, V]
+	Size() int
+	GetKeyFrequency(key K) (int, error)
+}
+
+// Model is a simple reference implementation of the LFU cache.
+// All operations are O(capacity), it is intended to be obviously correct rather than fast
+type Model[K comparable, V any] struct {
+	capacity int
+	clock    uint64
+	entries  []modelEntry[K, V]
+}
+
+type modelEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	freq     int
+	lastUsed uint64
+}
+
+// NewModel creates an empty reference model with the given capacity
+func NewModel[K comparable, V any](capacity int) *Model[K, V] {
+	return &Model[K, V]{capacity: capacity}
+}
+
+func (m *Model[K, V]) find(key K) int {
+	return slices.IndexFunc(m.entries, func(e modelEntry[K, V]) bool {
+		return e.key == key
+	})
+}
+
+func (m *Model[K, V]) use(i int) {
+	m.clock++
+	m.entries[i].freq++
+	m.entries[i].lastUsed = m.clock
+}
+
+// Get returns the value of the key and increments its frequency, or returns ErrKeyNotFound
+func (m *Model[K, V]) Get(key K) (V, error) {
+	i := m.find(key)
+	if i < 0 {
+		var zero V
+		return zero, ErrKeyNotFound
+	}
+	m.use(i)
+	return m.entries[i].value, nil
+}
+
+// Put updates or inserts the key, evicting the least frequently and then least recently used key if full
+func (m *Model[K, V]) Put(key K, value V) {
+	if i := m.find(key); i >= 0 {
+		m.use(i)
+		m.entries[i].value = value
+		return
+	}
+	if m.capacity == 0 {
+		return
+	}
+	if len(m.entries) == m.capacity {
+		victim := 0
+		for i := range m.entries {
+			if compareEntries(m.entries[i], m.entries[victim]) < 0 {
+				victim = i
+			}
+		}
+		m.entries = slices.Delete(m.entries, victim, victim+1)
+	}
+	m.clock++
+	m.entries = append(m.entries, modelEntry[K, V]{key: key, value: value, freq: 1, lastUsed: m.clock})
+}
+
+// compareEntries orders entries in eviction order: by frequency, then by recency
+func compareEntries[K comparable, V any](a, b modelEntry[K, V]) int {
+	return cmp.Or(cmp.Compare(a.freq, b.freq), cmp.Compare(a.lastUsed, b.lastUsed))
+}
+
+// All returns the iterator in descending order of frequency, most recently used first within ties
+func (m *Model[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sorted := slices.SortedFunc(slices.Values(m.entries), func(a, b modelEntry[K, V]) int {
+			return compareEntries(b, a)
+		})
+		for _, e := range sorted {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Size returns the number of entries
+func (m *Model[K, V]) Size() int {
+	return len(m.entries)
+}
+
+// GetKeyFrequency returns the frequency of the key, or returns ErrKeyNotFound
+func (m *Model[K, V]) GetKeyFrequency(key K) (int, error) {
+	i := m.find(key)
+	if i < 0 {
+		return 0, ErrKeyNotFound
+	}
+	return m.entries[i].freq, nil
+}
@@ -0,0 +1,653 @@
+// Package linkedlist provides a generic doubly linked list implemented as a ring.
+package linkedlist
+
+import "iter"
+
+// Node of list
+// O(1) memory
+type Node[V any] struct {
+	// next and prev pointers in the doubly-linked list of elements
+	next *Node[V]
+	prev *Node[V]
+	// owner of the node, nil if the node has been removed from list
+	owner *owner[V]
+	Value V
+}
+
+// owner binds nodes to the list they belong to.
+// Clear and Splice detach or transfer all nodes at once by updating owners instead of every node.
+type owner[V any] struct {
+	list *listImpl[V]
+}
+
+// Next returns the next node pointer after the given node pointer
+func (n *Node[V]) Next() *Node[V] {
+	return n.next
+}
+
+// Prev returns the prev node pointer of the given node pointer
+func (n *Node[V]) Prev() *Node[V] {
+	return n.prev
+}
+
+// List returns the list the node belongs to or nil if the node has been removed
+func (n *Node[V]) List() List[V] {
+	if n.owner == nil || n.owner.list == nil {
+		return nil
+	}
+	return n.owner.list
+}
+
+// List - O(size) memory
+// Methods accepting nodes panic if a node is not an element of the list,
+// e.g. it belongs to another list or has already been removed
+type List[V any] interface {
+	// Move moves element node before element start
+	// without memory allocations. Size not changes
+	// O(1)
+	Move(node, start *Node[V])
+
+	// MoveToFront moves node to the front of list without memory allocations.
+	// O(1)
+	MoveToFront(node *Node[V])
+
+	// MoveToBack moves node to the back of list without memory allocations.
+	// O(1)
+	MoveToBack(node *Node[V])
+
+	// Swap exchanges positions of nodes a and b by relinking them, node values stay in their nodes
+	// O(1)
+	Swap(a, b *Node[V])
+
+	// Push inserts a new element with the given value before node start and increments l.size.
+	// Returns a new node which was added
+	// O(1)
+	Push(value V, start *Node[V]) *Node[V]
+
+	// PushFront inserts a new element with the given value at the front of list.
+	// If list is bounded and full, the last node is removed first.
+	// Returns a new node which was added
+	// O(1)
+	PushFront(value V) *Node[V]
+
+	// PushFrontEvict works like PushFront and additionally returns the value of the node
+	// removed from the back of a full bounded list and true, or zero value and false if nothing was removed
+	// O(1)
+	PushFrontEvict(value V) (*Node[V], V, bool)
+
+	// MaxLen returns the maximum length of a bounded list or 0 if list is unbounded.
+	// Only PushFront and PushFrontEvict respect the bound, other insertions may exceed it
+	// O(1)
+	MaxLen() int
+
+	// PushBack inserts a new element with the given value at the back of list.
+	// Returns a new node which was added
+	// O(1)
+	PushBack(value V) *Node[V]
+
+	// InsertBefore inserts a new element with the given value immediately before node.
+	// Returns a new node which was added
+	// O(1)
+	InsertBefore(value V, node *Node[V]) *Node[V]
+
+	// InsertAfter inserts a new element with the given value immediately after node.
+	// Returns a new node which was added
+	// O(1)
+	InsertAfter(value V, node *Node[V]) *Node[V]
+
+	// Splice moves all nodes of other list before node at without memory allocations.
+	// other becomes empty, l.size is increased by the size of other
+	// O(1)
+	Splice(other List[V], at *Node[V])
+
+	// Split detaches the suffix of list starting at node at into a new list without memory allocations
+	// for the nodes. If at is the fictitious head node, the new list is empty.
+	// O(k), where k is the size of the suffix
+	Split(at *Node[V]) List[V]
+
+	// RotateTo repositions the fictitious head of the ring so that node becomes the first node of list.
+	// O(1)
+	RotateTo(node *Node[V])
+
+	// Rotate moves the first n nodes to the back of list, negative n moves the last -n nodes to the front.
+	// O(min(n, size-n))
+	Rotate(n int)
+
+	// Pop deletes the last node from list and decrements l.size
+	// O(1)
+	Pop()
+
+	// PopFront deletes the first node from list, decrements l.size and returns its value.
+	// If size == 0 function will return zero value and false
+	// O(1)
+	PopFront() (V, bool)
+
+	// PopBack deletes the last node from list, decrements l.size and returns its value.
+	// If size == 0 function will return zero value and false
+	// O(1)
+	PopBack() (V, bool)
+
+	// Remove deletes the given node from list, decrements l.size and returns the node's value.
+	// The node is not reused unless it is handed back by Release, so operations on it keep panicking
+	// O(1)
+	Remove(node *Node[V]) V
+
+	// Release hands a node removed from list or from any other list back for reuse by subsequent insertions,
+	// so they do not allocate memory. The node must not be used afterwards: once it is reused,
+	// its misuse can not be detected anymore. Panics if the node is an element of a list or already released
+	// O(1)
+	Release(node *Node[V])
+
+	// Grow preallocates nodes, so that another n insertions do not allocate memory.
+	// Nodes are allocated one by one, so a node kept after its removal does not keep the others in memory
+	// O(n)
+	Grow(n int)
+
+	// Clear unlinks all nodes from list and resets l.size
+	// O(1)
+	Clear()
+
+	// Size returns number of elements in list
+	// O(1)
+	Size() int
+
+	// Front returns the first node of list or nil if list is empty
+	// If size == 0 function will return nil
+	// O(1)
+	Front() *Node[V]
+
+	// Back returns the last node of list or nil if list is empty
+	// If size == 0 function will return nil
+	// O(1)
+	Back() *Node[V]
+
+	// Find returns the first node from the front whose value satisfies pred, or nil if there is none
+	// O(size)
+	Find(pred func(V) bool) *Node[V]
+
+	// ContainsFunc reports whether at least one value of list satisfies pred
+	// O(size)
+	ContainsFunc(pred func(V) bool) bool
+
+	// SortFunc sorts list in place in ascending order as determined by cmp, like slices.SortFunc.
+	// The sort is stable and relinks nodes without memory allocations, so node references stay valid
+	// O(size * log(size))
+	SortFunc(cmp func(a, b V) int)
+
+	// Head returns the fictitious node of list
+	// O(1)
+	Head() *Node[V]
+
+	// RemoveFunc removes all elements whose values satisfy pred and returns the number of removed elements
+	// O(size)
+	RemoveFunc(pred func(V) bool) int
+
+	// All returns the iterator.
+	// The yielded element may be removed during iteration
+	// O(size)
+	All() iter.Seq[V]
+
+	// Backward returns the iterator from the back of list to the front.
+	// The yielded element may be removed during iteration
+	// O(size)
+	Backward() iter.Seq[V]
+
+	// Nodes returns the iterator over nodes from the front of list to the back.
+	// The next node is captured before yielding, so the yielded node may be removed during iteration
+	// O(size)
+	Nodes() iter.Seq[*Node[V]]
+
+	// BackwardNodes returns the iterator over nodes from the back of list to the front.
+	// The previous node is captured before yielding, so the yielded node may be removed during iteration
+	// O(size)
+	BackwardNodes() iter.Seq[*Node[V]]
+}
+
+// listImpl represents a doubly linked list implementation. It is implemented as a ring.
+// If maxLen > 0 the list is bounded and works as a ring buffer for PushFront.
+// Nodes of list refer to one of its owners, new nodes refer to the last one.
+// Nodes preallocated by Grow or released by Release are kept in the free list linked by next pointers
+// and taken by insertions, prev pointers of free nodes refer to the nodes themselves.
+type listImpl[V any] struct {
+	head     *Node[V]
+	size     int
+	maxLen   int
+	owners   []*owner[V]
+	free     *Node[V]
+	freeSize int
+}
+
+// New creates an empty list
+func New[V any]() List[V] {
+	l := &listImpl[V]{
+		head: &Node[V]{},
+	}
+	l.head.prev = l.head
+	l.head.next = l.head
+	l.owners = []*owner[V]{{list: l}}
+	return l
+}
+
+// check panics if node is not an element of list, e.g. it belongs to another list or has been removed
+func (l *listImpl[V]) check(node *Node[V]) {
+	if node == nil || node.owner == nil || node.owner.list != l {
+		panic("linkedlist: node is not an element of the list (foreign or already removed)")
+	}
+}
+
+// checkPosition is like check, but also accepts the fictitious head node
+func (l *listImpl[V]) checkPosition(node *Node[V]) {
+	if node != l.head {
+		l.check(node)
+	}
+}
+
+// detach makes all current nodes of list foreign to it
+func (l *listImpl[V]) detach() {
+	for _, o := range l.owners {
+		o.list = nil
+	}
+	l.owners = []*owner[V]{{list: l}}
+}
+
+// NewBounded creates an empty list which holds at most maxLen elements pushed with PushFront.
+// Panics if maxLen is not positive
+func NewBounded[V any](maxLen int) List[V] {
+	if maxLen <= 0 {
+		panic("invalid max length")
+	}
+	l := New[V]().(*listImpl[V])
+	l.maxLen = maxLen
+	return l
+}
+
+func (l *listImpl[V]) Move(node, start *Node[V]) {
+	l.check(node)
+	l.checkPosition(start)
+	node.prev.next = node.next
+	node.next.prev = node.prev
+	node.prev = start.prev
+	node.next = start
+	start.prev.next = node
+	start.prev = node
+}
+
+func (l *listImpl[V]) MoveToFront(node *Node[V]) {
+	if l.head.next == node {
+		return
+	}
+	l.Move(node, l.head.next)
+}
+
+func (l *listImpl[V]) MoveToBack(node *Node[V]) {
+	if l.head.prev == node {
+		return
+	}
+	l.Move(node, l.head)
+}
+
+func (l *listImpl[V]) Swap(a, b *Node[V]) {
+	l.check(a)
+	l.check(b)
+	switch {
+	case a == b:
+	case a.next == b:
+		l.Move(b, a)
+	case b.next == a:
+		l.Move(a, b)
+	default:
+		aNext := a.next
+		l.Move(a, b)
+		l.Move(b, aNext)
+	}
+}
+
+func (l *listImpl[V]) Push(value V, start *Node[V]) *Node[V] {
+	l.checkPosition(start)
+	last := l.newNode()
+	last.next = start
+	last.prev = start.prev
+	last.owner = l.owners[len(l.owners)-1]
+	last.Value = value
+	last.prev.next = last
+	last.next.prev = last
+	l.size++
+	return last
+}
+
+func (l *listImpl[V]) PushFront(value V) *Node[V] {
+	node, _, _ := l.PushFrontEvict(value)
+	return node
+}
+
+func (l *listImpl[V]) PushFrontEvict(value V) (*Node[V], V, bool) {
+	var (
+		evicted V
+		ok      bool
+	)
+	if l.maxLen > 0 && l.size >= l.maxLen {
+		evicted, ok = l.PopBack()
+	}
+	return l.Push(value, l.head.next), evicted, ok
+}
+
+func (l *listImpl[V]) MaxLen() int {
+	return l.maxLen
+}
+
+func (l *listImpl[V]) PushBack(value V) *Node[V] {
+	return l.Push(value, l.head)
+}
+
+func (l *listImpl[V]) InsertBefore(value V, node *Node[V]) *Node[V] {
+	return l.Push(value, node)
+}
+
+func (l *listImpl[V]) InsertAfter(value V, node *Node[V]) *Node[V] {
+	l.checkPosition(node)
+	return l.Push(value, node.next)
+}
+
+func (l *listImpl[V]) Splice(other List[V], at *Node[V]) {
+	l.checkPosition(at)
+	o, ok := other.(*listImpl[V])
+	if !ok {
+		for value, ok := other.PopFront(); ok; value, ok = other.PopFront() {
+			l.Push(value, at)
+		}
+		return
+	}
+	if o == l || o.size == 0 {
+		return
+	}
+	first, last := o.head.next, o.head.prev
+	first.prev = at.prev
+	at.prev.next = first
+	last.next = at
+	at.prev = last
+	l.size += o.size
+
+	for _, owner := range o.owners {
+		owner.list = l
+	}
+	l.owners = append(o.owners, l.owners...)
+	o.owners = []*owner[V]{{list: o}}
+
+	o.head.next = o.head
+	o.head.prev = o.head
+	o.size = 0
+}
+
+func (l *listImpl[V]) Split(at *Node[V]) List[V] {
+	l.checkPosition(at)
+	suffix := New[V]().(*listImpl[V])
+	if at == l.head {
+		return suffix
+	}
+	count := 0
+	for cur := at; cur != l.head; cur = cur.next {
+		cur.owner = suffix.owners[0]
+		count++
+	}
+	first, last := at, l.head.prev
+	first.prev.next = l.head
+	l.head.prev = first.prev
+	l.size -= count
+
+	first.prev = suffix.head
+	last.next = suffix.head
+	suffix.head.next = first
+	suffix.head.prev = last
+	suffix.size = count
+	return suffix
+}
+
+func (l *listImpl[V]) RotateTo(node *Node[V]) {
+	l.checkPosition(node)
+	if node == l.head || node == l.head.next {
+		return
+	}
+	l.head.prev.next = l.head.next
+	l.head.next.prev = l.head.prev
+	l.head.prev = node.prev
+	l.head.next = node
+	node.prev.next = l.head
+	node.prev = l.head
+}
+
+func (l *listImpl[V]) Rotate(n int) {
+	if l.size == 0 {
+		return
+	}
+	n %= l.size
+	if n < 0 {
+		n += l.size
+	}
+	if n == 0 {
+		return
+	}
+	cur := l.head
+	if n <= l.size/2 {
+		for range n {
+			cur = cur.next
+		}
+		cur = cur.next
+	} else {
+		for range l.size - n {
+			cur = cur.prev
+		}
+	}
+	l.RotateTo(cur)
+}
+
+func (l *listImpl[V]) Pop() {
+	l.Remove(l.Back())
+}
+
+func (l *listImpl[V]) PopFront() (V, bool) {
+	if l.size == 0 {
+		var zero V
+		return zero, false
+	}
+	return l.Remove(l.head.next), true
+}
+
+func (l *listImpl[V]) PopBack() (V, bool) {
+	if l.size == 0 {
+		var zero V
+		return zero, false
+	}
+	return l.Remove(l.head.prev), true
+}
+
+func (l *listImpl[V]) Remove(node *Node[V]) V {
+	l.check(node)
+	node.next.prev = node.prev
+	node.prev.next = node.next
+	node.next = nil
+	node.prev = nil
+	node.owner = nil
+	l.size--
+	return node.Value
+}
+
+func (l *listImpl[V]) Release(node *Node[V]) {
+	if node == nil || node == l.head || node.List() != nil || node.prev == node {
+		panic("linkedlist: node is an element of a list or already released")
+	}
+	var zero V
+	node.Value = zero
+	node.owner = nil
+	l.recycle(node)
+}
+
+func (l *listImpl[V]) Grow(n int) {
+	for l.freeSize < n {
+		l.recycle(&Node[V]{})
+	}
+}
+
+// recycle puts the node into the free list
+func (l *listImpl[V]) recycle(node *Node[V]) {
+	node.prev = node
+	node.next = l.free
+	l.free = node
+	l.freeSize++
+}
+
+// newNode takes a node from the free list or allocates a new one
+func (l *listImpl[V]) newNode() *Node[V] {
+	if l.free == nil {
+		return &Node[V]{}
+	}
+	node := l.free
+	l.free = node.next
+	l.freeSize--
+	return node
+}
+
+func (l *listImpl[V]) Clear() {
+	l.detach()
+	l.head.next = l.head
+	l.head.prev = l.head
+	l.size = 0
+}
+
+func (l *listImpl[V]) Size() int {
+	return l.size
+}
+
+func (l *listImpl[V]) Front() *Node[V] {
+	if l.size == 0 {
+		return nil
+	}
+	return l.head.next
+}
+
+func (l *listImpl[V]) Back() *Node[V] {
+	if l.size == 0 {
+		return nil
+	}
+	return l.head.prev
+}
+
+func (l *listImpl[V]) Find(pred func(V) bool) *Node[V] {
+	for cur := l.head.next; cur != l.head; cur = cur.next {
+		if pred(cur.Value) {
+			return cur
+		}
+	}
+	return nil
+}
+
+func (l *listImpl[V]) ContainsFunc(pred func(V) bool) bool {
+	return l.Find(pred) != nil
+}
+
+func (l *listImpl[V]) SortFunc(cmp func(a, b V) int) {
+	if l.size < 2 {
+		return
+	}
+	first := mergeSort(l.head.next, l.size, cmp)
+
+	prev := l.head
+	for cur := first; cur != nil; cur = cur.next {
+		cur.prev = prev
+		prev = cur
+	}
+	prev.next = l.head
+	l.head.prev = prev
+	l.head.next = first
+}
+
+// mergeSort sorts the chain of n nodes starting at first using only next pointers.
+// Returns the first node of the sorted chain, which is terminated by nil
+func mergeSort[V any](first *Node[V], n int, cmp func(a, b V) int) *Node[V] {
+	if n == 1 {
+		first.next = nil
+		return first
+	}
+	mid := first
+	for range n/2 - 1 {
+		mid = mid.next
+	}
+	second := mid.next
+	a := mergeSort(first, n/2, cmp)
+	b := mergeSort(second, n-n/2, cmp)
+
+	var fake Node[V]
+	tail := &fake
+	for a != nil && b != nil {
+		if cmp(a.Value, b.Value) <= 0 {
+			tail.next, a = a, a.next
+		} else {
+			tail.next, b = b, b.next
+		}
+		tail = tail.next
+	}
+	if a != nil {
+		tail.next = a
+	} else {
+		tail.next = b
+	}
+	return fake.next
+}
+
+func (l *listImpl[V]) Head() *Node[V] {
+	return l.head
+}
+
+func (l *listImpl[V]) RemoveFunc(pred func(V) bool) int {
+	removed := 0
+	for node := range l.Nodes() {
+		if pred(node.Value) {
+			l.Remove(node)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (l *listImpl[V]) All() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for node := range l.Nodes() {
+			if !yield(node.Value) {
+				return
+			}
+		}
+	}
+}
+
+func (l *listImpl[V]) Backward() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for node := range l.BackwardNodes() {
+			if !yield(node.Value) {
+				return
+			}
+		}
+	}
+}
+
+func (l *listImpl[V]) Nodes() iter.Seq[*Node[V]] {
+	return func(yield func(*Node[V]) bool) {
+		for cur := l.head.next; cur != l.head; {
+			next := cur.next
+			if !yield(cur) {
+				return
+			}
+			cur = next
+		}
+	}
+}
+
+func (l *listImpl[V]) BackwardNodes() iter.Seq[*Node[V]] {
+	return func(yield func(*Node[V]) bool) {
+		for cur := l.head.prev; cur != l.head; {
+			prev := cur.prev
+			if !yield(cur) {
+				return
+			}
+			cur = prev
+		}
+	}
+}
@@ -0,0 +1,472 @@
+package linkedlist
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushFrontPushBack(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	two := l.PushBack(2)
+	l.PushFront(1)
+	l.PushBack(3)
+
+	require.Equal(t, 3, l.Size())
+	require.Equal(t, []int{1, 2, 3}, slices.Collect(l.All()))
+	require.Equal(t, 1, l.Front().Value)
+	require.Equal(t, 3, l.Back().Value)
+	require.Equal(t, 2, two.Value)
+}
+
+func TestInsertBeforeInsertAfter(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	two := l.PushBack(2)
+	l.InsertBefore(1, two)
+	l.InsertAfter(3, two)
+	l.InsertAfter(4, l.Back())
+
+	require.Equal(t, 4, l.Size())
+	require.Equal(t, []int{1, 2, 3, 4}, slices.Collect(l.All()))
+}
+
+func TestMoveToFrontMoveToBack(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	one := l.PushBack(1)
+	two := l.PushBack(2)
+	three := l.PushBack(3)
+
+	l.MoveToFront(three)
+	require.Equal(t, []int{3, 1, 2}, slices.Collect(l.All()))
+
+	l.MoveToFront(three)
+	require.Equal(t, []int{3, 1, 2}, slices.Collect(l.All()))
+
+	l.MoveToBack(one)
+	require.Equal(t, []int{3, 2, 1}, slices.Collect(l.All()))
+
+	l.MoveToBack(one)
+	l.MoveToFront(two)
+	require.Equal(t, []int{2, 3, 1}, slices.Collect(l.All()))
+	require.Equal(t, 3, l.Size())
+}
+
+func TestRemoveAndPopReturnValues(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	l.PushBack(1)
+	two := l.PushBack(2)
+	l.PushBack(3)
+	l.PushBack(4)
+
+	require.Equal(t, 2, l.Remove(two))
+
+	value, ok := l.PopFront()
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	value, ok = l.PopBack()
+	require.True(t, ok)
+	require.Equal(t, 4, value)
+
+	value, ok = l.PopBack()
+	require.True(t, ok)
+	require.Equal(t, 3, value)
+
+	_, ok = l.PopFront()
+	require.False(t, ok)
+	_, ok = l.PopBack()
+	require.False(t, ok)
+	require.Equal(t, 0, l.Size())
+}
+
+func TestBackward(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	require.Empty(t, slices.Collect(l.Backward()))
+
+	for i := range 5 {
+		l.PushBack(i)
+	}
+	require.Equal(t, []int{4, 3, 2, 1, 0}, slices.Collect(l.Backward()))
+
+	var firstTwo []int
+	for v := range l.Backward() {
+		if len(firstTwo) == 2 {
+			break
+		}
+		firstTwo = append(firstTwo, v)
+	}
+	require.Equal(t, []int{4, 3}, firstTwo)
+}
+
+func TestNodesRemoveWhileWalking(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	for i := range 6 {
+		l.PushBack(i)
+	}
+
+	for node := range l.Nodes() {
+		if node.Value%2 == 0 {
+			l.Remove(node)
+		}
+	}
+	require.Equal(t, []int{1, 3, 5}, slices.Collect(l.All()))
+
+	for node := range l.BackwardNodes() {
+		if node.Value > 1 {
+			l.Remove(node)
+		}
+	}
+	require.Equal(t, []int{1}, slices.Collect(l.All()))
+	require.Equal(t, 1, l.Size())
+}
+
+func TestSplice(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	l.PushBack(1)
+	four := l.PushBack(4)
+
+	other := New[int]()
+	two := other.PushBack(2)
+	other.PushBack(3)
+
+	l.Splice(other, four)
+	require.Equal(t, []int{1, 2, 3, 4}, slices.Collect(l.All()))
+	require.Equal(t, []int{4, 3, 2, 1}, slices.Collect(l.Backward()))
+	require.Equal(t, 4, l.Size())
+	require.Equal(t, 0, other.Size())
+	require.Empty(t, slices.Collect(other.All()))
+
+	l.Remove(two)
+	require.Equal(t, []int{1, 3, 4}, slices.Collect(l.All()))
+
+	other.PushBack(5)
+	l.Splice(other, l.Head())
+	require.Equal(t, []int{1, 3, 4, 5}, slices.Collect(l.All()))
+
+	l.Splice(New[int](), l.Front())
+	require.Equal(t, 4, l.Size())
+}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	var three *Node[int]
+	for i := range 6 {
+		node := l.PushBack(i)
+		if i == 3 {
+			three = node
+		}
+	}
+
+	suffix := l.Split(three)
+	require.Equal(t, []int{0, 1, 2}, slices.Collect(l.All()))
+	require.Equal(t, []int{2, 1, 0}, slices.Collect(l.Backward()))
+	require.Equal(t, 3, l.Size())
+	require.Equal(t, []int{3, 4, 5}, slices.Collect(suffix.All()))
+	require.Equal(t, []int{5, 4, 3}, slices.Collect(suffix.Backward()))
+	require.Equal(t, 3, suffix.Size())
+
+	empty := l.Split(l.Head())
+	require.Equal(t, 0, empty.Size())
+
+	whole := l.Split(l.Front())
+	require.Equal(t, 0, l.Size())
+	require.Empty(t, slices.Collect(l.All()))
+	require.Equal(t, []int{0, 1, 2}, slices.Collect(whole.All()))
+}
+
+func TestRotate(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	l.Rotate(3)
+
+	var two *Node[int]
+	for i := range 5 {
+		node := l.PushBack(i)
+		if i == 2 {
+			two = node
+		}
+	}
+
+	l.RotateTo(two)
+	require.Equal(t, []int{2, 3, 4, 0, 1}, slices.Collect(l.All()))
+	require.Equal(t, []int{1, 0, 4, 3, 2}, slices.Collect(l.Backward()))
+
+	l.Rotate(1)
+	require.Equal(t, []int{3, 4, 0, 1, 2}, slices.Collect(l.All()))
+
+	l.Rotate(4)
+	require.Equal(t, []int{2, 3, 4, 0, 1}, slices.Collect(l.All()))
+
+	l.Rotate(-2)
+	require.Equal(t, []int{0, 1, 2, 3, 4}, slices.Collect(l.All()))
+
+	l.Rotate(10)
+	require.Equal(t, []int{0, 1, 2, 3, 4}, slices.Collect(l.All()))
+	require.Equal(t, 5, l.Size())
+}
+
+func TestClear(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	for i := range 3 {
+		l.PushBack(i)
+	}
+
+	l.Clear()
+	require.Equal(t, 0, l.Size())
+	require.Nil(t, l.Front())
+	require.Empty(t, slices.Collect(l.All()))
+
+	l.PushBack(7)
+	require.Equal(t, []int{7}, slices.Collect(l.All()))
+}
+
+func TestBoundedPushFront(t *testing.T) {
+	t.Parallel()
+
+	l := NewBounded[int](3)
+	require.Equal(t, 3, l.MaxLen())
+
+	for i := range 3 {
+		_, _, ok := l.PushFrontEvict(i)
+		require.False(t, ok)
+	}
+
+	_, evicted, ok := l.PushFrontEvict(3)
+	require.True(t, ok)
+	require.Equal(t, 0, evicted)
+
+	l.PushFront(4)
+	require.Equal(t, []int{4, 3, 2}, slices.Collect(l.All()))
+	require.Equal(t, 3, l.Size())
+
+	require.Equal(t, 0, New[int]().MaxLen())
+	require.Panics(t, func() {
+		NewBounded[int](0)
+	})
+}
+
+func TestSwap(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	nodes := make([]*Node[int], 0, 4)
+	for i := range 4 {
+		nodes = append(nodes, l.PushBack(i))
+	}
+
+	l.Swap(nodes[0], nodes[3])
+	require.Equal(t, []int{3, 1, 2, 0}, slices.Collect(l.All()))
+
+	l.Swap(nodes[1], nodes[2])
+	require.Equal(t, []int{3, 2, 1, 0}, slices.Collect(l.All()))
+
+	l.Swap(nodes[0], nodes[1])
+	require.Equal(t, []int{3, 2, 0, 1}, slices.Collect(l.All()))
+	require.Equal(t, []int{1, 0, 2, 3}, slices.Collect(l.Backward()))
+
+	l.Swap(nodes[2], nodes[2])
+	require.Equal(t, []int{3, 2, 0, 1}, slices.Collect(l.All()))
+	require.Equal(t, 1, nodes[1].Value)
+	require.Equal(t, 4, l.Size())
+}
+
+func TestFindContainsFunc(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	for i := range 5 {
+		l.PushBack(i * 10)
+	}
+
+	node := l.Find(func(v int) bool { return v > 15 })
+	require.NotNil(t, node)
+	require.Equal(t, 20, node.Value)
+	require.Equal(t, 30, node.Next().Value)
+
+	require.Nil(t, l.Find(func(v int) bool { return v > 100 }))
+	require.True(t, l.ContainsFunc(func(v int) bool { return v == 40 }))
+	require.False(t, l.ContainsFunc(func(v int) bool { return v == 41 }))
+	require.False(t, New[int]().ContainsFunc(func(int) bool { return true }))
+}
+
+func TestSortFunc(t *testing.T) {
+	t.Parallel()
+
+	type pair struct {
+		key, order int
+	}
+
+	l := New[pair]()
+	expected := make([]pair, 0, 100)
+	for i := range 100 {
+		p := pair{key: rand.N(10), order: i}
+		l.PushBack(p)
+		expected = append(expected, p)
+	}
+	byKey := func(a, b pair) int {
+		return cmp.Compare(a.key, b.key)
+	}
+
+	l.SortFunc(byKey)
+	slices.SortStableFunc(expected, byKey)
+
+	require.Equal(t, expected, slices.Collect(l.All()))
+	slices.Reverse(expected)
+	require.Equal(t, expected, slices.Collect(l.Backward()))
+	require.Equal(t, 100, l.Size())
+}
+
+func TestMisusePanics(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	other := New[int]()
+	node := l.PushBack(1)
+	foreign := other.PushBack(2)
+
+	require.Panics(t, func() { l.Remove(foreign) })
+	require.Panics(t, func() { l.Move(foreign, l.Head()) })
+	require.Panics(t, func() { l.InsertBefore(3, foreign) })
+	require.Equal(t, []int{1}, slices.Collect(l.All()))
+	require.Equal(t, []int{2}, slices.Collect(other.All()))
+
+	l.Remove(node)
+	require.Panics(t, func() { l.Remove(node) })
+	require.Equal(t, 0, l.Size())
+
+	l.Splice(other, l.Head())
+	require.NotPanics(t, func() { l.Remove(foreign) })
+
+	cleared := l.PushBack(4)
+	l.Clear()
+	require.Panics(t, func() { l.MoveToFront(cleared) })
+	require.Panics(t, func() { l.Remove(cleared) })
+}
+
+func TestGrow(t *testing.T) {
+	l := New[int]()
+	l.Grow(200)
+
+	allocs := testing.AllocsPerRun(10, func() {
+		for i := range 10 {
+			l.PushBack(i)
+		}
+		for l.Size() > 0 {
+			l.Pop()
+		}
+	})
+	require.Zero(t, allocs)
+
+	node := l.PushBack(1)
+	l.Remove(node)
+	require.Nil(t, node.Next())
+	other := l.PushBack(2)
+	require.NotSame(t, node, other)
+	require.Panics(t, func() { l.Remove(node) })
+	require.Equal(t, []int{2}, slices.Collect(l.All()))
+}
+
+func TestRelease(t *testing.T) {
+	l := New[int]()
+	other := New[int]()
+
+	node := l.PushBack(1)
+	require.Panics(t, func() { l.Release(node) })
+	require.Panics(t, func() { l.Release(l.Head()) })
+	l.Remove(node)
+	l.Release(node)
+	require.Panics(t, func() { l.Release(node) })
+	require.Panics(t, func() { l.Remove(node) })
+	require.Panics(t, func() { l.MoveToFront(node) })
+	require.Nil(t, node.List())
+
+	require.Same(t, node, l.PushFront(2))
+	require.Equal(t, l, node.List())
+	require.Equal(t, []int{2}, slices.Collect(l.All()))
+
+	cleared := other.PushBack(3)
+	other.Clear()
+	l.Release(cleared)
+	require.Same(t, cleared, l.PushBack(4))
+	require.Equal(t, []int{2, 4}, slices.Collect(l.All()))
+
+	allocs := testing.AllocsPerRun(10, func() {
+		for i := range 10 {
+			node := l.PushBack(i)
+			l.Remove(node)
+			l.Release(node)
+		}
+	})
+	require.Zero(t, allocs)
+}
+
+func TestNodeList(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	other := New[int]()
+	node := l.PushBack(1)
+	moved := other.PushBack(2)
+
+	require.Equal(t, l, node.List())
+	require.Equal(t, other, moved.List())
+
+	l.Splice(other, l.Head())
+	require.Equal(t, l, moved.List())
+
+	suffix := l.Split(moved)
+	require.Equal(t, suffix, moved.List())
+
+	l.Remove(node)
+	require.Nil(t, node.List())
+
+	kept := l.PushBack(3)
+	l.Clear()
+	require.Nil(t, kept.List())
+}
+
+func TestRemoveDuringIteration(t *testing.T) {
+	t.Parallel()
+
+	l := New[int]()
+	for i := range 6 {
+		l.PushBack(i)
+	}
+
+	visited := make([]int, 0, 6)
+	for v := range l.All() {
+		visited = append(visited, v)
+		l.Remove(l.Front())
+	}
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5}, visited)
+	require.Equal(t, 0, l.Size())
+
+	for i := range 6 {
+		l.PushBack(i)
+	}
+	require.Equal(t, 3, l.RemoveFunc(func(v int) bool { return v%2 == 1 }))
+	require.Equal(t, []int{0, 2, 4}, slices.Collect(l.All()))
+	require.Equal(t, 3, l.Size())
+}